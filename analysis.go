@@ -0,0 +1,274 @@
+package pia
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// Reference is a substitution point found by [pia.Analyze].
+type Reference struct {
+	// File is the path of the file holding the substitution point, and Line is the line it starts on.
+	File string
+	Line int
+	// Key is the key of the substitution point including any modifiers, and Source is its context key.
+	Key    string
+	Source string
+	// Resolved reports whether the key resolves, in which case Delegate is the context key of the property source that
+	// supplied the value. Delegate is empty when the value is a literal default. Err holds the reason for the key not
+	// resolving.
+	Resolved bool
+	Delegate string
+	Err      error
+	// Deferred reports whether resolving the key involves a property source that is left for the transaction to
+	// resolve at run time, in which case the key is neither resolved nor unresolved.
+	Deferred bool
+}
+
+// Analysis is the outcome of analyzing the substitution points of a transaction.
+type Analysis struct {
+	References []Reference
+	// Problems are issues that prevented parts of the transaction from being analyzed, such as malformed substitution
+	// points or referenced files that cannot be read.
+	Problems []error
+}
+
+// Unresolved returns the references whose keys do not resolve.
+func (a Analysis) Unresolved() []Reference {
+	var unresolved []Reference
+	for _, ref := range a.References {
+		if !ref.Resolved && !ref.Deferred {
+			unresolved = append(unresolved, ref)
+		}
+	}
+	return unresolved
+}
+
+// OK reports whether every key resolves and no problems were found.
+func (a Analysis) OK() bool {
+	return len(a.Problems) == 0 && len(a.Unresolved()) == 0
+}
+
+// WriteText writes the analysis as a table with one row per reference, followed by any problems.
+func (a Analysis) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCATION\tKEY\tSOURCE\tSTATUS")
+	for _, ref := range a.References {
+		status := "unresolved: " + errorText(ref.Err)
+		switch {
+		case ref.Deferred:
+			status = "resolved at run time"
+		case ref.Resolved && ref.Delegate == "":
+			status = "default"
+		case ref.Resolved:
+			status = "resolved by " + ref.Delegate
+		}
+		fmt.Fprintf(tw, "%s:%d\t%s\t%s\t%s\n", ref.File, ref.Line, ref.Key, ref.Source, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, problem := range a.Problems {
+		if _, err := fmt.Fprintf(w, "problem: %s\n", problem); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d references, %d unresolved, %d problems\n",
+		len(a.References), len(a.Unresolved()), len(a.Problems))
+	return err
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return "unknown reason"
+	}
+	return err.Error()
+}
+
+// Analyze finds every substitution point of the transaction file at path, along with those of the files it inherits
+// from and of the files it refers to for its body and hooks unless they have been opted out of interpolation, and
+// resolves them using the supplied delegates, keyed by their context keys. Keys are resolved the way
+// [pia.FallbackResolverDecorator] and [pia.DelegatingKeyResolver] resolve them during execution, which means that any
+// side effects of the delegates, such as asking for a passphrase, take place. The exception are delegates whose values
+// are only known while transactions execute, or which should not be consulted more often than necessary, namely the
+// [pia.Session] and any [pia.ExecResolver]. Keys involving those are reported as deferred instead. Values are never part
+// of the analysis. Of the options, only [pia.WithRoot] is considered. The returned error is only set if the transaction
+// file cannot be read.
+func Analyze(path string, delegates map[string]KeyResolver, opts ...ParseOption) (Analysis, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Analysis{}, err
+	}
+	a := &analyzer{}
+	traced := make(map[string]KeyResolver, len(delegates))
+	for name, delegate := range delegates {
+		traced[name] = tracedResolver{name: name, delegate: delegate, analyzer: a, deferred: deferred(delegate)}
+	}
+	a.resolver = FallbackResolverDecorator{Delegate: DelegatingKeyResolver{Delegates: traced}}
+	Reset(a.resolver)
+
+	cfg, err := a.decode(path, src)
+	if err != nil {
+		if !errors.Is(err, errScanned) {
+			a.problem(fmt.Errorf("%s: %w", path, err))
+		}
+		return a.analysis, nil
+	}
+	wd := filepath.Dir(path)
+	err = inherit(&cfg, wd, options.root, func(path string) (transaction, error) {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return transaction{}, err
+		}
+		return a.decode(path, src)
+	})
+	if err != nil {
+		if !errors.Is(err, errScanned) {
+			a.problem(err)
+		}
+		return a.analysis, nil
+	}
+	inputs := append([]input{cfg.Body.input}, cfg.before...)
+	for _, in := range append(inputs, cfg.after...) {
+		file := in.File
+		if in.Inline != "" || file == "" || !in.interpolated() {
+			continue
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(wd, file)
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			a.problem(err)
+			continue
+		}
+		a.scan(file, src)
+	}
+	return a.analysis, nil
+}
+
+// analyzer records the substitution points of the files it scans as it resolves them.
+type analyzer struct {
+	analysis Analysis
+	resolver KeyResolver
+	file     string
+	src      []byte
+	// at returns the offset of the substitution point being resolved within src.
+	at func() int64
+	// supplier is the context key of the first delegate to resolve a key while resolving a substitution point, and
+	// deferred reports whether a deferred delegate was consulted while doing so.
+	supplier string
+	deferred bool
+}
+
+func (a *analyzer) problem(err error) {
+	a.analysis.Problems = append(a.analysis.Problems, err)
+}
+
+// errScanned is returned by [pia.analyzer.decode] for files that could not be interpolated, which has already been
+// recorded as a problem.
+var errScanned = errors.New("file could not be interpolated")
+
+// decode records the substitution points of src, which is the content of the transaction file at path, while decoding
+// it the way [pia.ParseTransaction] does.
+func (a *analyzer) decode(path string, src []byte) (transaction, error) {
+	a.file, a.src = path, src
+	cfg, err := decodeTransaction(bytes.NewReader(src), func(p placeholder) (string, error) {
+		a.at = func() int64 { return p.Offset }
+		return a.Resolve(p.Key)
+	})
+	var ierr *InterpolationError
+	switch {
+	case errors.As(err, &ierr):
+		a.problem(fmt.Errorf("%s:%d: %w", path, line(src, ierr.Offset), ierr.Err))
+		return cfg, errScanned
+	case errors.Is(err, io.EOF):
+		return cfg, nil
+	}
+	return cfg, err
+}
+
+// scan records the substitution points of src, which is the content of file. Unresolved keys are interpolated as empty
+// strings.
+func (a *analyzer) scan(file string, src []byte) {
+	a.file, a.src = file, src
+	ip := WrapReader(a, bytes.NewReader(src))
+	a.at = func() int64 { return ip.start }
+	if _, err := io.Copy(io.Discard, ip); err != nil {
+		var ierr *InterpolationError
+		if errors.As(err, &ierr) {
+			err = fmt.Errorf("%s:%d: %w", file, line(src, ierr.Offset), ierr.Err)
+		}
+		a.problem(err)
+	}
+}
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (a *analyzer) Resolve(k string) (string, error) {
+	a.supplier, a.deferred = "", false
+	v, err := a.resolver.Resolve(k)
+	source, _, _ := strings.Cut(modifiers(k)[0], ":")
+	ref := Reference{
+		File:     a.file,
+		Line:     line(a.src, a.at()),
+		Key:      k,
+		Source:   source,
+		Resolved: err == nil,
+		Delegate: a.supplier,
+		Err:      err,
+	}
+	if a.deferred {
+		ref.Resolved, ref.Delegate, ref.Err, ref.Deferred = false, "", nil, true
+	}
+	a.analysis.References = append(a.analysis.References, ref)
+	return v, nil
+}
+
+// line returns the number of the line holding the byte at offset within src.
+func line(src []byte, offset int64) int {
+	return bytes.Count(src[:min(offset, int64(len(src)))], []byte("\n")) + 1
+}
+
+// deferred reports whether keys of the delegate are left unresolved by an analysis.
+func deferred(delegate KeyResolver) bool {
+	switch delegate.(type) {
+	case *Session, *ExecResolver:
+		return true
+	}
+	return false
+}
+
+// tracedResolver records the name of the delegate with the analyzer whenever the delegate resolves a key. Deferred
+// delegates are never consulted, they are only recorded as such.
+type tracedResolver struct {
+	name     string
+	delegate KeyResolver
+	analyzer *analyzer
+	deferred bool
+}
+
+func (t tracedResolver) Resolve(k string) (string, error) {
+	if t.deferred {
+		t.analyzer.deferred = true
+		return "", fmt.Errorf("%w: %s is resolved at run time", ErrKeyNotFound, t.name)
+	}
+	v, err := t.delegate.Resolve(k)
+	if err == nil && t.analyzer.supplier == "" {
+		t.analyzer.supplier = t.name
+	}
+	return v, err
+}
+
+// Reset implements the [pia.Resetter] interface by resetting the delegate if it implements it.
+func (t tracedResolver) Reset() {
+	Reset(t.delegate)
+}
@@ -0,0 +1,131 @@
+package pia_test
+
+import (
+	"errors"
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	wd := t.TempDir()
+	files := map[string]string{
+		"tx.yml": strings.Join([]string{
+			"method: POST",
+			"url:",
+			"  target: https://${props:host}/users",
+			"headers:",
+			"  Authorization: Bearer ${session:token}",
+			"  X-Region: ${props:region|${env:REGION}}",
+			"  X-Trace: ${props:trace|none}",
+			"body:",
+			"  file: ${props:body}",
+			"hooks:",
+			"  before:",
+			"    file: before.sqk",
+			"    interpolate: false",
+			"  after:",
+			"    file: after.sqk",
+		}, "\n"),
+		"body.json":  "{\n  \"name\": \"${props:name|upper}\"\n}\n",
+		"before.sqk": "print(\"${props:ignored}\");\n",
+		"after.sqk":  "assert(response.status == ${props:status});\n",
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, name), []byte(content), 0666))
+	}
+	delegates := map[string]pia.KeyResolver{
+		"props": pia.MapResolver{"host": "example.com", "body": "body.json", "name": "pia"},
+		"env":   pia.MapResolver{"REGION": "eu"},
+	}
+	analysis, err := pia.Analyze(filepath.Join(wd, "tx.yml"), delegates)
+	assert.Nil(t, err)
+	assert.Empty(t, analysis.Problems)
+
+	type row struct {
+		file     string
+		line     int
+		key      string
+		source   string
+		resolved bool
+		delegate string
+	}
+	var rows []row
+	for _, ref := range analysis.References {
+		rows = append(rows, row{filepath.Base(ref.File), ref.Line, ref.Key, ref.Source, ref.Resolved, ref.Delegate})
+		assert.Equal(t, ref.Resolved, ref.Err == nil, ref.Key)
+	}
+	assert.Equal(t, []row{
+		{"tx.yml", 3, "props:host", "props", true, "props"},
+		{"tx.yml", 5, "session:token", "session", false, ""},
+		{"tx.yml", 6, "props:region|${env:REGION}", "props", true, "env"},
+		{"tx.yml", 7, "props:trace|none", "props", true, ""},
+		{"tx.yml", 9, "props:body", "props", true, "props"},
+		{"body.json", 2, "props:name|upper", "props", true, "props"},
+		{"after.sqk", 1, "props:status", "props", false, ""},
+	}, rows)
+	assert.False(t, analysis.OK())
+	assert.Len(t, analysis.Unresolved(), 2)
+
+	var b strings.Builder
+	assert.Nil(t, analysis.WriteText(&b))
+	assert.Contains(t, b.String(), "resolved by env")
+	assert.Contains(t, b.String(), "7 references, 2 unresolved, 0 problems")
+}
+
+func TestAnalyze_Deferred(t *testing.T) {
+	wd := t.TempDir()
+	tx := strings.Join([]string{
+		"method: GET",
+		"url:",
+		"  target: https://example.com",
+		"headers:",
+		"  Authorization: Bearer ${session:token}",
+		"  X-Otp: ${exec:otp}",
+		"  X-Trace: ${props:trace|${session:trace}}",
+	}, "\n")
+	assert.Nil(t, os.WriteFile(filepath.Join(wd, "tx.yml"), []byte(tx), 0666))
+	delegates := map[string]pia.KeyResolver{
+		"props":   pia.MapResolver{},
+		"session": pia.NewSession(),
+		"exec":    pia.NewExecResolver(wd, map[string]pia.Command{"otp": {Run: "touch used"}}),
+	}
+	analysis, err := pia.Analyze(filepath.Join(wd, "tx.yml"), delegates)
+	assert.Nil(t, err)
+	assert.True(t, analysis.OK())
+	assert.Len(t, analysis.References, 3)
+	for _, ref := range analysis.References {
+		assert.True(t, ref.Deferred, ref.Key)
+	}
+	_, err = os.Stat(filepath.Join(wd, "used"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "commands should not run during an analysis")
+
+	var b strings.Builder
+	assert.Nil(t, analysis.WriteText(&b))
+	assert.Contains(t, b.String(), "resolved at run time")
+}
+
+func TestAnalyze_Problems(t *testing.T) {
+	wd := t.TempDir()
+	files := map[string]string{
+		"tx.yml":    "method: GET\nurl:\n  target: https://example.com\nbody:\n  file: body.json\nhooks:\n  before:\n    file: missing.sqk\n",
+		"body.json": "name=${props:name\n\nid=1\n",
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, name), []byte(content), 0666))
+	}
+	analysis, err := pia.Analyze(filepath.Join(wd, "tx.yml"), map[string]pia.KeyResolver{})
+	assert.Nil(t, err)
+	assert.Empty(t, analysis.References)
+	assert.Len(t, analysis.Problems, 2)
+	assert.True(t, errors.Is(analysis.Problems[0], pia.ErrUnterminatedPlaceholder))
+	assert.Contains(t, analysis.Problems[0].Error(), "body.json:1")
+	assert.True(t, errors.Is(analysis.Problems[1], os.ErrNotExist))
+	assert.False(t, analysis.OK())
+
+	_, err = pia.Analyze(filepath.Join(wd, "missing.yml"), nil)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
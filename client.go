@@ -0,0 +1,97 @@
+package pia
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// client represents the configuration of an [http.Client] in its textual YAML state. Every field is optional and an
+// unset field leaves the corresponding behavior of the Go standard library untouched.
+type client struct {
+	Timeout            time.Duration `yaml:"timeout,omitempty"`
+	FollowRedirects    *bool         `yaml:"follow_redirects,omitempty"`
+	MaxRedirects       int           `yaml:"max_redirects,omitempty"`
+	Proxy              string        `yaml:"proxy,omitempty"`
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify,omitempty"`
+	CAFile             string        `yaml:"ca_file,omitempty"`
+	CertFile           string        `yaml:"cert_file,omitempty"`
+	KeyFile            string        `yaml:"key_file,omitempty"`
+	HTTP2              *bool         `yaml:"http2,omitempty"`
+}
+
+// build returns an [http.Client] that mirrors the configuration. Any file paths are resolved relative to wd unless they
+// are absolute.
+func (c *client) build(wd string) (*http.Client, error) {
+	path := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(wd, p)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	cfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(path(c.CAFile))
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("both cert_file and key_file must be set to use a client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(path(c.CertFile), path(c.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = cfg
+	if c.HTTP2 != nil {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(*c.HTTP2)
+		transport.Protocols = protocols
+	}
+
+	hc := &http.Client{
+		Transport: transport,
+		Timeout:   c.Timeout,
+	}
+	if c.FollowRedirects != nil && !*c.FollowRedirects {
+		hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+			// Returning this error makes the client hand back the redirect response itself rather than following it.
+			return http.ErrUseLastResponse
+		}
+	} else if c.MaxRedirects > 0 {
+		hc.CheckRedirect = func(_ *http.Request, via []*http.Request) error {
+			if len(via) > c.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", c.MaxRedirects)
+			}
+			return nil
+		}
+	}
+	return hc, nil
+}
@@ -0,0 +1,158 @@
+package pia
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/ernilsson/pia/squeak"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransaction_Execute_Client(t *testing.T) {
+	execute := func(t *testing.T, wd, cfg string) (*Response, error) {
+		tx, err := ParseTransaction(wd, strings.NewReader(cfg))
+		assert.Nil(t, err)
+		return tx.Execute(squeak.NewInterpreter(wd, io.Discard))
+	}
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/final" {
+			return
+		}
+		http.Redirect(w, r, "/final", http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	t.Run("timeout", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer srv.Close()
+		_, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+client:
+  timeout: 10ms
+`, srv.URL))
+		assert.ErrorContains(t, err, "Client.Timeout exceeded")
+	})
+
+	t.Run("redirects followed by default", func(t *testing.T) {
+		res, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s/start
+client:
+  timeout: 1s
+`, redirecting.URL))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("redirects not followed", func(t *testing.T) {
+		res, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s/start
+client:
+  follow_redirects: false
+`, redirecting.URL))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Equal(t, "/final", res.Header.Get("Location"))
+	})
+
+	t.Run("redirects exceeding max count", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/again", http.StatusFound)
+		}))
+		defer srv.Close()
+		_, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+client:
+  max_redirects: 2
+`, srv.URL))
+		assert.ErrorContains(t, err, "stopped after 2 redirects")
+	})
+
+	t.Run("self-signed certificate", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+		_, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+`, srv.URL))
+		assert.NotNil(t, err)
+
+		res, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+client:
+  insecure_skip_verify: true
+`, srv.URL))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		wd := t.TempDir()
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, "ca.pem"), ca, 0666))
+		res, err = execute(t, wd, fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+client:
+  ca_file: ca.pem
+`, srv.URL))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("http2 toggle", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+		res, err := execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+client:
+  insecure_skip_verify: true
+`, srv.URL))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, res.ProtoMajor)
+
+		res, err = execute(t, "", fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+client:
+  insecure_skip_verify: true
+  http2: false
+`, srv.URL))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, res.ProtoMajor)
+	})
+
+	t.Run("client certificate without key", func(t *testing.T) {
+		_, err := ParseTransaction("", strings.NewReader(`
+method: GET
+url:
+  target: https://localhost
+client:
+  cert_file: client.pem
+`))
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ernilsson/pia"
+	"os"
+	"path/filepath"
+)
+
+// check lists the substitution points of a transaction, or of every transaction of a collection, along with whether
+// they resolve, without sending any requests. It fails if any of them does not resolve.
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	flags := sourceFlags(fs, false)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<transaction|collection> [--props file] [--env name] [--env-dir dir] [--exec file]")
+	}
+	src, err := flags()
+	if err != nil {
+		return err
+	}
+	delegates, err := src.delegates(pia.NewSession())
+	if err != nil {
+		return err
+	}
+
+	path := positional[0]
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	paths := []string{path}
+	if info.IsDir() || filepath.Base(path) == pia.CollectionManifest {
+		c, err := pia.LoadCollection(path)
+		if err != nil {
+			return err
+		}
+		paths = c.Transactions
+	}
+	failed := 0
+	for i, path := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# %s\n", path)
+		analysis, err := pia.Analyze(path, delegates, pia.WithRoot("."))
+		if err != nil {
+			return err
+		}
+		if err := analysis.WriteText(os.Stdout); err != nil {
+			return err
+		}
+		if !analysis.OK() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d transactions failed the check", failed, len(paths))
+	}
+	return nil
+}
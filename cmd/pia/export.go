@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// export writes a transaction in a format understood by other tools to the standard output. The format is given by the
+// first argument.
+func export(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: pia export curl <transaction> [--props file] [--env name] [--env-dir dir] [--exec file]")
+	}
+	switch args[0] {
+	case "curl":
+		return exportCurl(args[1:])
+	default:
+		return fmt.Errorf("unsupported export format: %s", args[0])
+	}
+}
+
+func exportCurl(args []string) error {
+	fs := flag.NewFlagSet("export curl", flag.ExitOnError)
+	flags := sourceFlags(fs, false)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<transaction> [--props file] [--env name] [--env-dir dir] [--exec file]")
+	}
+	resolver, _, err := loadResolver(flags)
+	if err != nil {
+		return err
+	}
+	tx, err := transaction(positional[0], resolver)
+	if err != nil {
+		return err
+	}
+	cmd, err := tx.Curl()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(cmd)
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ernilsson/pia"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imports converts requests described in a format understood by other tools into transaction files. The format is
+// given by the first argument.
+func imports(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: pia import curl|postman|openapi ...")
+	}
+	switch args[0] {
+	case "curl":
+		return importCurl(args[1:])
+	case "postman":
+		return importPostman(args[1:])
+	case "openapi":
+		return importOpenAPI(args[1:])
+	default:
+		return fmt.Errorf("unsupported import format: %s", args[0])
+	}
+}
+
+func importCurl(args []string) error {
+	fs := flag.NewFlagSet("import curl", flag.ExitOnError)
+	output := fs.String("o", "", "path of the transaction file to write, defaults to the standard output")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	var cmd string
+	switch len(positional) {
+	case 0:
+		// Without a command as argument it is read from the standard input, which avoids having to quote the command.
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		cmd = string(src)
+	case 1:
+		cmd = positional[0]
+	default:
+		return usage(fs, "[command] [-o file]")
+	}
+	var buf bytes.Buffer
+	if err := pia.ImportCurl(&buf, strings.TrimSpace(cmd)); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	// Existing transaction files are never overwritten since they may well contain hooks written by hand.
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// files is a repeatable flag holding file paths.
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *files) Set(path string) error {
+	*f = append(*f, path)
+	return nil
+}
+
+func importPostman(args []string) error {
+	fs := flag.NewFlagSet("import postman", flag.ExitOnError)
+	output := fs.String("o", "", "directory to write the transactions to, defaults to one named after the collection")
+	var environments files
+	fs.Var(&environments, "env", "path to a Postman environment to convert into a property file, may be repeated")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<collection.json> [-o dir] [--env environment.json]...")
+	}
+	return importTree(positional[0], *output, func(dir string, r io.Reader) ([]string, error) {
+		warnings, err := pia.ImportPostman(dir, r)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range environments {
+			if err := importPostmanEnvironment(dir, path); err != nil {
+				return nil, err
+			}
+		}
+		return warnings, nil
+	})
+}
+
+func importOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("import openapi", flag.ExitOnError)
+	output := fs.String("o", "", "directory to write the transactions to, defaults to one named after the document")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<openapi.yml|openapi.json> [-o dir]")
+	}
+	return importTree(positional[0], *output, pia.ImportOpenAPI)
+}
+
+// importTree imports the file at path into the directory dir using the supplied importer, and reports the warnings of
+// the importer on the standard error. The directory defaults to one named after the file.
+func importTree(path, dir string, importer func(dir string, r io.Reader) ([]string, error)) error {
+	if dir == "" {
+		dir = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	warnings, err := importer(dir, f)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	fmt.Printf("imported %s into %s with %d warnings\n", path, dir, len(warnings))
+	return nil
+}
+
+func importPostmanEnvironment(dir, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := pia.ImportPostmanEnvironment(dir, f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
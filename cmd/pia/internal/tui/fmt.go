@@ -1,10 +1,12 @@
 package tui
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/ernilsson/pia"
 	"io"
-	"net/http"
 	"strings"
+	"time"
 )
 
 type Formatter[T fmt.Stringer] interface {
@@ -13,11 +15,26 @@ type Formatter[T fmt.Stringer] interface {
 
 type FormatterFunc[T fmt.Stringer] func(io.Writer, T) error
 
-func ResponseFormatter(w io.Writer, res *http.Response) error {
+func ResponseFormatter(w io.Writer, res *pia.Response) error {
 	_, err := fmt.Fprintf(w, "Status: %s\n", res.Status)
 	if err != nil {
 		return err
 	}
+	_, err = fmt.Fprintf(
+		w,
+		"Protocol: %s\nRemote address: %s\nSize: %d bytes\nTimings: DNS %s, connect %s, TLS %s, first byte %s, total %s\n\n",
+		res.Proto,
+		res.RemoteAddr,
+		res.Size,
+		res.Timings.DNS.Round(time.Microsecond),
+		res.Timings.Connect.Round(time.Microsecond),
+		res.Timings.TLS.Round(time.Microsecond),
+		res.Timings.FirstByte.Round(time.Microsecond),
+		res.Timings.Total.Round(time.Microsecond),
+	)
+	if err != nil {
+		return err
+	}
 	for k, v := range res.Header {
 		_, err = fmt.Fprintf(w, "%s: %s\n", k, strings.Join(v, ", "))
 		if err != nil {
@@ -40,3 +57,183 @@ func ResponseFormatter(w io.Writer, res *http.Response) error {
 	}
 	return nil
 }
+
+// JSONResponseFormatter writes the response as a single JSON document. The body is embedded as JSON if it is valid
+// JSON, otherwise it is embedded as a string.
+func JSONResponseFormatter(w io.Writer, res *pia.Response) error {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	type timings struct {
+		DNS       float64 `json:"dns_ms"`
+		Connect   float64 `json:"connect_ms"`
+		TLS       float64 `json:"tls_ms"`
+		FirstByte float64 `json:"first_byte_ms"`
+		Total     float64 `json:"total_ms"`
+	}
+	doc := struct {
+		Status     string            `json:"status"`
+		StatusCode int               `json:"status_code"`
+		Proto      string            `json:"proto"`
+		RemoteAddr string            `json:"remote_addr"`
+		Size       int64             `json:"size"`
+		Timings    timings           `json:"timings"`
+		Headers    map[string]string `json:"headers"`
+		Body       any               `json:"body"`
+	}{
+		Status:     res.Status,
+		StatusCode: res.StatusCode,
+		Proto:      res.Proto,
+		RemoteAddr: res.RemoteAddr,
+		Size:       res.Size,
+		Timings: timings{
+			DNS:       ms(res.Timings.DNS),
+			Connect:   ms(res.Timings.Connect),
+			TLS:       ms(res.Timings.TLS),
+			FirstByte: ms(res.Timings.FirstByte),
+			Total:     ms(res.Timings.Total),
+		},
+		Headers: make(map[string]string),
+	}
+	for k, v := range res.Header {
+		doc.Headers[k] = strings.Join(v, ", ")
+	}
+	if res.Body != nil {
+		raw, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if json.Valid(raw) {
+			doc.Body = json.RawMessage(raw)
+		} else if len(raw) > 0 {
+			doc.Body = string(raw)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// SummaryFormatter writes one line per transaction in the summary followed by the total number of passed, failed and
+// skipped transactions.
+func SummaryFormatter(w io.Writer, summary pia.Summary) error {
+	for _, r := range summary {
+		if r.Skipped {
+			if _, err := fmt.Fprintf(w, "SKIP %s\n", r.File); err != nil {
+				return err
+			}
+			continue
+		}
+		outcome := "PASS"
+		if !r.Passed() {
+			outcome = "FAIL"
+		}
+		_, err := fmt.Fprintf(w, "%s %s %s %s %d %s\n", outcome, r.File, r.Method, r.Target, r.Status, r.Duration.Round(time.Millisecond))
+		if err != nil {
+			return err
+		}
+		if r.Err != nil {
+			_, err = fmt.Fprintf(w, "     %s\n", r.Err)
+			if err != nil {
+				return err
+			}
+		}
+		for _, test := range r.Tests {
+			if err := testResultFormatter(w, "     ", test); err != nil {
+				return err
+			}
+		}
+	}
+	passed := len(summary) - summary.Failed() - summary.Skipped()
+	_, err := fmt.Fprintf(
+		w, "\n%d transactions, %d passed, %d failed, %d skipped\n",
+		len(summary), passed, summary.Failed(), summary.Skipped(),
+	)
+	return err
+}
+
+// JSONSummaryFormatter writes the summary as a single JSON document.
+func JSONSummaryFormatter(w io.Writer, summary pia.Summary) error {
+	type test struct {
+		Name       string  `json:"name"`
+		Passed     bool    `json:"passed"`
+		Message    string  `json:"message,omitempty"`
+		DurationMS float64 `json:"duration_ms"`
+	}
+	type result struct {
+		File       string  `json:"file"`
+		Method     string  `json:"method"`
+		Target     string  `json:"target"`
+		Status     int     `json:"status"`
+		DurationMS float64 `json:"duration_ms"`
+		Passed     bool    `json:"passed"`
+		Skipped    bool    `json:"skipped,omitempty"`
+		Error      string  `json:"error,omitempty"`
+		Tests      []test  `json:"tests"`
+	}
+	doc := struct {
+		Passed  int      `json:"passed"`
+		Failed  int      `json:"failed"`
+		Skipped int      `json:"skipped"`
+		Results []result `json:"results"`
+	}{
+		Passed:  len(summary) - summary.Failed() - summary.Skipped(),
+		Failed:  summary.Failed(),
+		Skipped: summary.Skipped(),
+		Results: make([]result, 0, len(summary)),
+	}
+	for _, r := range summary {
+		res := result{
+			File:       r.File,
+			Method:     r.Method,
+			Target:     r.Target,
+			Status:     r.Status,
+			DurationMS: float64(r.Duration) / float64(time.Millisecond),
+			Passed:     r.Passed(),
+			Skipped:    r.Skipped,
+			Tests:      make([]test, 0, len(r.Tests)),
+		}
+		if r.Err != nil {
+			res.Error = r.Err.Error()
+		}
+		for _, t := range r.Tests {
+			res.Tests = append(res.Tests, test{
+				Name:       t.Name,
+				Passed:     t.Passed,
+				Message:    t.Message,
+				DurationMS: float64(t.Duration) / float64(time.Millisecond),
+			})
+		}
+		doc.Results = append(doc.Results, res)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// TestResultsFormatter writes one line per test result stating whether it passed, followed by the failure message of
+// any failed test.
+func TestResultsFormatter(w io.Writer, results []pia.TestResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprint(w, "Tests:\n"); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := testResultFormatter(w, "  ", r); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprint(w, "\n")
+	return err
+}
+
+func testResultFormatter(w io.Writer, indent string, r pia.TestResult) error {
+	if r.Passed {
+		_, err := fmt.Fprintf(w, "%sPASS %s %s\n", indent, r.Name, r.Duration.Round(time.Millisecond))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%sFAIL %s %s: %s\n", indent, r.Name, r.Duration.Round(time.Millisecond), r.Message)
+	return err
+}
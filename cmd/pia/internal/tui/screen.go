@@ -3,6 +3,7 @@ package tui
 import (
 	"bytes"
 	"fmt"
+	"github.com/ernilsson/pia"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"golang.design/x/clipboard"
@@ -67,9 +68,13 @@ func newFinder(wd string) *finder {
 }
 
 type finder struct {
-	tree            *tview.TreeView
-	executeCallback func(string)
-	viewCallback    func(string)
+	tree               *tview.TreeView
+	executeCallback    func(string)
+	collectionCallback func(string)
+	viewCallback       func(string)
+	curlCallback       func(string)
+	importCallback     func(string)
+	checkCallback      func(string)
 }
 
 func (f *finder) root() tview.Primitive {
@@ -88,14 +93,51 @@ func (f *finder) input(event *tcell.EventKey) *tcell.EventKey {
 		path := f.tree.GetCurrentNode().GetReference().(string)
 		f.viewCallback(path)
 		return nil
-	case 'x':
-		if f.executeCallback == nil {
+	case 'd':
+		if f.checkCallback == nil {
+			return event
+		}
+		if f.isSelectedNodeDir() {
+			return nil
+		}
+		path := f.tree.GetCurrentNode().GetReference().(string)
+		f.checkCallback(path)
+		return nil
+	case 'C':
+		if f.curlCallback == nil {
 			return event
 		}
 		if f.isSelectedNodeDir() {
 			return nil
 		}
 		path := f.tree.GetCurrentNode().GetReference().(string)
+		f.curlCallback(path)
+		return nil
+	case 'I':
+		if f.importCallback == nil {
+			return event
+		}
+		node := f.tree.GetCurrentNode()
+		if !f.isSelectedNodeDir() {
+			nodes := f.tree.GetPath(node)
+			node = nodes[len(nodes)-2]
+		}
+		path := node.GetReference().(string)
+		f.importCallback(path)
+		f.reload(node, path)
+		return nil
+	case 'x':
+		path := f.tree.GetCurrentNode().GetReference().(string)
+		if f.isSelectedNodeDir() || filepath.Base(path) == pia.CollectionManifest {
+			if f.collectionCallback == nil {
+				return event
+			}
+			f.collectionCallback(path)
+			return nil
+		}
+		if f.executeCallback == nil {
+			return event
+		}
 		f.executeCallback(path)
 		return nil
 	case rune(tcell.KeyEnter):
@@ -124,13 +166,25 @@ func (f *finder) toggle(node *tview.TreeNode, path string) {
 		node.SetExpanded(!node.IsExpanded())
 		return
 	}
+	f.load(node, path)
+}
+
+// reload replaces the children of node with the current contents of the directory at path, e.g. after a file has been
+// added to it.
+func (f *finder) reload(node *tview.TreeNode, path string) {
+	node.ClearChildren()
+	f.load(node, path)
+	node.SetExpanded(true)
+}
+
+func (f *finder) load(node *tview.TreeNode, path string) {
 	files, err := os.ReadDir(path)
 	if err != nil {
 		panic(err)
 	}
 	for _, file := range files {
 		yml := strings.HasSuffix(file.Name(), ".yml") || strings.HasSuffix(file.Name(), ".yaml")
-		if !yml && !file.IsDir() {
+		if !yml && !file.IsDir() || file.Name() == pia.DefaultsFile {
 			continue
 		}
 		n := tview.NewTreeNode(file.Name()).
@@ -192,3 +246,82 @@ func (h *history) push(e entry) {
 	}
 	h.transactions[0] = &e
 }
+
+func newHeader(environment string) *header {
+	h := &header{
+		text: tview.NewTextView().SetDynamicColors(true),
+	}
+	h.set(environment)
+	return h
+}
+
+// header is a single line, always visible above the pages, showing the active environment.
+type header struct {
+	text        *tview.TextView
+	environment string
+}
+
+func (h *header) root() tview.Primitive {
+	return h.text
+}
+
+func (h *header) set(environment string) {
+	h.environment = environment
+	switch {
+	case environment == "":
+		h.text.SetText(" pia | environment: [white::b]none[-::-] (base properties only)")
+	case strings.Contains(strings.ToLower(environment), "prod"):
+		// Production environments stand out to make it obvious where requests are sent.
+		h.text.SetText(fmt.Sprintf(" pia | environment: [black:red:b] %s [-:-:-]", tview.Escape(environment)))
+	default:
+		h.text.SetText(fmt.Sprintf(" pia | environment: [green::b]%s[-::-]", tview.Escape(environment)))
+	}
+}
+
+func newPicker(environments pia.Environments) *picker {
+	return &picker{
+		environments: environments,
+		list:         tview.NewList().ShowSecondaryText(false),
+	}
+}
+
+// picker lists the available environments and lets the user pick the one to use for interpolation.
+type picker struct {
+	environments   pia.Environments
+	list           *tview.List
+	selectCallback func(string)
+}
+
+func (p *picker) root() tview.Primitive {
+	return p.list
+}
+
+// enter refreshes the list of environments and shows the picker. Once an environment has been picked the page that was
+// shown before the picker is restored.
+func (p *picker) enter(active string, pages *tview.Pages) {
+	previous, _ := pages.GetFrontPage()
+	if previous == "environments" {
+		return
+	}
+	pick := func(name string) func() {
+		return func() {
+			pages.SwitchToPage(previous)
+			if p.selectCallback != nil {
+				p.selectCallback(name)
+			}
+		}
+	}
+	p.list.Clear()
+	p.list.AddItem("(none)", "", 0, pick(""))
+	names, err := p.environments.Names()
+	if err != nil {
+		p.list.AddItem(fmt.Sprintf("failed to list environments: %s", err), "", 0, nil)
+	}
+	for _, name := range names {
+		p.list.AddItem(name, "", 0, pick(name))
+		if name == active {
+			p.list.SetCurrentItem(p.list.GetItemCount() - 1)
+		}
+	}
+	pages.SwitchToPage("environments")
+}
@@ -2,21 +2,34 @@ package tui
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"github.com/ernilsson/pia"
 	"github.com/ernilsson/pia/squeak"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"golang.design/x/clipboard"
+	"gopkg.in/yaml.v3"
 	"io"
+	"maps"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 type App struct {
-	resolver pia.KeyResolver
+	// wd is the directory the app is rooted in, which is where the search for defaults files ends.
+	wd        string
+	resolver  pia.KeyResolver
+	delegates map[string]pia.KeyResolver
+	session   *pia.Session
 	*tview.Application
 	pages   *tview.Pages
+	header  *header
+	picker  *picker
 	console *console
 	content *content
 	finder  *finder
@@ -29,27 +42,95 @@ func (a *App) view(path string) {
 		panic(err)
 	}
 	defer tx.Close()
-	src, err := io.ReadAll(pia.WrapReader(a.resolver, tx))
+	pia.Reset(a.resolver)
+	src, err := io.ReadAll(pia.WrapReader(a.masked(), tx))
 	if err != nil {
 		panic(err)
 	}
 	a.display(string(src))
 }
 
+// masked returns a resolver equivalent to that of the app except for secrets being masked, which keeps them off the
+// screen when displaying preprocessed files.
+func (a *App) masked() pia.KeyResolver {
+	delegates := maps.Clone(a.delegates)
+	if secrets, ok := delegates["secret"]; ok {
+		delegates["secret"] = mask{secrets}
+	}
+	return pia.FallbackResolverDecorator{
+		Delegate: pia.DelegatingKeyResolver{
+			Delegates: delegates,
+		},
+	}
+}
+
+// mask resolves keys using the embedded resolver but replaces every value it resolves with asterisks.
+type mask struct {
+	pia.KeyResolver
+}
+
+func (m mask) Resolve(k string) (string, error) {
+	if _, err := m.KeyResolver.Resolve(k); err != nil {
+		return "", err
+	}
+	return "********", nil
+}
+
+// check displays the analysis of the substitution points of the transaction at path.
+func (a *App) check(path string) {
+	analysis, err := pia.Analyze(path, a.delegates, pia.WithRoot(a.wd))
+	if err != nil {
+		a.display(fmt.Sprintf("could not analyze %s: %s", path, err))
+		return
+	}
+	var buf bytes.Buffer
+	if err := analysis.WriteText(&buf); err != nil {
+		panic(err)
+	}
+	a.display(buf.String())
+}
+
 func (a *App) execute(path string) {
+	// The analysis is reported as a pre-flight check whenever some key does not resolve, but the transaction is
+	// executed all the same since keys of body and hook files may well resolve once the before hook has run.
+	var preflight bytes.Buffer
+	if analysis, err := pia.Analyze(path, a.delegates, pia.WithRoot(a.wd)); err == nil && !analysis.OK() {
+		preflight.WriteString("# the pre-flight check found keys that do not resolve\n")
+		if err := analysis.WriteText(&preflight); err != nil {
+			panic(err)
+		}
+		preflight.WriteString("\n")
+	}
 	cfg, err := os.ReadFile(path)
 	if err != nil {
 		panic(err)
 	}
-	tx, err := pia.ParseTransaction(filepath.Dir(path), pia.WrapReader(a.resolver, bytes.NewReader(cfg)))
+	pia.Reset(a.resolver)
+	tx, err := pia.ParseTransaction(
+		filepath.Dir(path),
+		bytes.NewReader(cfg),
+		pia.WithResolver(a.resolver),
+		pia.WithRoot(a.wd),
+	)
 	if err != nil {
-		panic(err)
+		a.display(fmt.Sprintf("%scould not parse %s: %s", preflight.String(), path, err))
+		return
 	}
-	res, err := tx.Execute(squeak.NewInterpreter(tx.WD, a.console.log))
+	in := squeak.NewInterpreter(tx.WD, a.console.log)
+	in.Declare("session", squeak.NewSessionObject(a.session))
+	res, err := tx.Execute(in)
+	if err != nil && res == nil {
+		a.display(fmt.Sprintf("%scould not execute %s: %s", preflight.String(), path, err))
+		return
+	}
+	buf := bytes.NewBuffer(preflight.Bytes())
 	if err != nil {
+		// The after hook failed, which is presented along with the response.
+		fmt.Fprintf(buf, "# after hook failed: %s\n", err)
+	}
+	if err := TestResultsFormatter(buf, pia.NewTestResults(path, in.DrainResults())); err != nil {
 		panic(err)
 	}
-	buf := bytes.NewBufferString("")
 	if err := ResponseFormatter(buf, res); err != nil {
 		panic(err)
 	}
@@ -63,6 +144,85 @@ func (a *App) execute(path string) {
 	a.display(text)
 }
 
+// curl displays the transaction at path as a curl command. Secrets are masked like they are when viewing a file, since
+// the command is both displayed and likely copied to the clipboard. The command is exported in full by pia export.
+func (a *App) curl(path string) {
+	cfg, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	pia.Reset(a.resolver)
+	tx, err := pia.ParseTransaction(
+		filepath.Dir(path),
+		bytes.NewReader(cfg),
+		pia.WithResolver(a.masked()),
+		pia.WithRoot(a.wd),
+	)
+	if err != nil {
+		a.display(fmt.Sprintf("could not export %s: %s", path, err))
+		return
+	}
+	cmd, err := tx.Curl()
+	if err != nil {
+		a.display(fmt.Sprintf("could not export %s: %s", path, err))
+		return
+	}
+	a.display(cmd)
+}
+
+// paste imports the curl command held by the clipboard as a new transaction file in the directory dir.
+func (a *App) paste(dir string) {
+	var buf bytes.Buffer
+	if err := pia.ImportCurl(&buf, strings.TrimSpace(string(clipboard.Read(clipboard.FmtText)))); err != nil {
+		a.display(fmt.Sprintf("could not import curl command from clipboard: %s", err))
+		return
+	}
+	var tx struct {
+		Method string `yaml:"method"`
+		URL    struct {
+			Target string `yaml:"target"`
+		} `yaml:"url"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &tx); err != nil {
+		panic(err)
+	}
+	name := strings.ToLower(tx.Method)
+	if u, err := url.Parse(tx.URL.Target); err == nil {
+		segment := path.Base(u.Path)
+		if segment == "/" || segment == "." {
+			segment = u.Hostname()
+		}
+		name += "-" + segment
+	}
+	file := filepath.Join(dir, name+".yml")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		file = filepath.Join(dir, fmt.Sprintf("%s-%d.yml", name, i))
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		panic(err)
+	}
+	a.display(fmt.Sprintf("# %s\n%s", file, buf.String()))
+}
+
+func (a *App) collection(path string) {
+	c, err := pia.LoadCollection(path)
+	if err != nil {
+		a.display(fmt.Sprintf("could not load collection %s: %s", path, err))
+		return
+	}
+	in := squeak.NewInterpreter(c.WD, a.console.log)
+	in.Declare("session", squeak.NewSessionObject(a.session))
+	summary := c.Run(a.resolver, in, pia.WithRoot(a.wd))
+	buf := bytes.NewBufferString("")
+	if err := SummaryFormatter(buf, summary); err != nil {
+		panic(err)
+	}
+	a.display(buf.String())
+}
+
 func (a *App) display(text string) {
 	a.content.text.SetText(text)
 	a.pages.SwitchToPage("content")
@@ -81,6 +241,9 @@ func (a *App) input(ev *tcell.EventKey) *tcell.EventKey {
 	case 'f':
 		a.pages.SwitchToPage("finder")
 		return nil
+	case 'e':
+		a.picker.enter(a.header.environment, a.pages)
+		return nil
 	case 'c':
 		a.console.enter()
 		if a.pages.HasPage("console") {
@@ -94,42 +257,84 @@ func (a *App) input(ev *tcell.EventKey) *tcell.EventKey {
 	}
 }
 
-func Run(wd string, props map[string]string) error {
+// switchEnvironment replaces the properties used for interpolation with those of the named environment.
+func (a *App) switchEnvironment(name string) {
+	props, err := a.picker.environments.Properties(name)
+	if err != nil {
+		a.display(fmt.Sprintf("could not switch to environment %s: %s", name, err))
+		return
+	}
+	a.delegates["props"] = pia.MapResolver(props)
+	a.header.set(name)
+}
+
+// Run starts the terminal user interface rooted in wd. The delegates are used as property sources for interpolation and
+// are expected to include the supplied session, which is also exposed to Squeak hooks. The properties delegate is
+// replaced whenever another of the environments is picked, starting out with the environment named by active.
+func Run(
+	wd string,
+	delegates map[string]pia.KeyResolver,
+	session *pia.Session,
+	envs pia.Environments,
+	active string,
+) error {
 	if err := clipboard.Init(); err != nil {
 		return err
 	}
 	app := App{
+		wd:          wd,
 		Application: tview.NewApplication(),
 		pages:       tview.NewPages(),
 		console:     newConsole(bytes.NewBufferString("")),
 		content:     newContent(),
 		finder:      newFinder(wd),
 		history:     newHistory(128),
+		header:      newHeader(active),
+		picker:      newPicker(envs),
+		session:     session,
+		delegates:   delegates,
 		resolver: pia.FallbackResolverDecorator{
 			Delegate: pia.DelegatingKeyResolver{
-				Delegates: map[string]pia.KeyResolver{
-					"env":   pia.EnvironmentResolver{},
-					"props": pia.MapResolver(props),
-				},
+				Delegates: delegates,
 			},
 		},
 	}
+	if vault, ok := delegates["secret"].(*pia.VaultResolver); ok {
+		prompt := vault.Passphrase
+		// The terminal is owned by the user interface, which has to step aside while the passphrase is asked for.
+		vault.Passphrase = func() (p string, err error) {
+			app.Suspend(func() {
+				p, err = prompt()
+			})
+			return p, err
+		}
+	}
 	app.history.viewCallback = func(e *entry) {
 		app.display(e.text)
 	}
 	app.finder.executeCallback = app.execute
+	app.finder.collectionCallback = app.collection
 	app.finder.viewCallback = app.view
+	app.finder.curlCallback = app.curl
+	app.finder.importCallback = app.paste
+	app.finder.checkCallback = app.check
+	app.picker.selectCallback = app.switchEnvironment
 	app.pages.AddPage("dashboard", tview.NewTextView().SetText(`
 	
 	pia - the postman alternative for technical people. 
 
 	Usage:
 	f - open finder window
-		x - execute currently selected file
+		x - execute currently selected file, or the collection of the selected directory
 			y - copy output to clipboard
 		v - view file contents after preprocessing
 			y - copy output to clipboard
+		d - check that every key of the selected file resolves
+		C - export currently selected file as a curl command, with secrets masked
+			y - copy output to clipboard
+		I - import the curl command in the clipboard into the selected directory
 	h - open history
+	e - pick the environment whose properties are used for interpolation
 	c - toggle console
 
 	<ESC> brings you back here.
@@ -139,6 +344,11 @@ func Run(wd string, props map[string]string) error {
 	app.pages.AddPage("finder", app.finder.root(), true, false)
 	app.pages.AddPage("content", app.content.root(), true, false)
 	app.pages.AddPage("history", app.history.root(), true, false)
+	app.pages.AddPage("environments", app.picker.root(), true, false)
 	app.SetInputCapture(app.input)
-	return app.SetRoot(app.pages, true).Run()
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(app.header.root(), 1, 0, false).
+		AddItem(app.pages, 0, 1, true)
+	return app.SetRoot(layout, true).Run()
 }
@@ -1,48 +1,169 @@
 package main
 
 import (
-	"bufio"
 	"flag"
+	"fmt"
+	"github.com/ernilsson/pia"
 	"github.com/ernilsson/pia/cmd/pia/internal/tui"
 	"log"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
+// command is the entrypoint of a subcommand. It receives the arguments that follow the name of the subcommand.
+type command func(args []string) error
+
+var commands = map[string]command{
+	"run":    run,
+	"export": export,
+	"secret": secret,
+	"check":  check,
+	"import": imports,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+	}
+	flags := sourceFlags(flag.CommandLine, true)
 	flag.Parse()
 	wd, err := os.Getwd()
 	if err != nil {
 		log.Fatalln(err)
 	}
-	props := make(map[string]string)
-	if flag.NArg() > 0 {
-		props, err = properties(flag.Arg(0))
-		if err != nil {
-			log.Fatalln(err)
-		}
+	src, err := flags()
+	if err != nil {
+		log.Fatalln(err)
 	}
-	if err := tui.Run(wd, props); err != nil {
+	session := pia.NewSession()
+	d, err := src.delegates(session)
+	if err != nil {
 		log.Fatalln(err)
 	}
+	if err := tui.Run(wd, d, session, src.envs, src.env); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// sources are the property sources selected by the flags registered through sourceFlags.
+type sources struct {
+	envs pia.Environments
+	// env is the name of the active environment.
+	env string
+	// exec is nil unless the commands property source has been opted into.
+	exec *pia.ExecResolver
+}
+
+// sourceFlags registers the flags which select the property sources used for interpolation on fs. The returned
+// function must be called once the flags have been parsed. When positional is set the base property file is given as
+// the first positional argument rather than with the --props flag.
+func sourceFlags(fs *flag.FlagSet, positional bool) func() (sources, error) {
+	var base *string
+	if !positional {
+		base = fs.String("props", "", "path to a base property file used for interpolation")
+	}
+	name := fs.String("env", "", "name of the environment whose property file is layered over the base property file")
+	dir := fs.String("env-dir", pia.EnvironmentsDir, "directory holding the property files of named environments")
+	commands := fs.String("exec", "", "path to a file defining the commands whose output is available for interpolation")
+	return func() (sources, error) {
+		src := sources{
+			envs: pia.Environments{
+				Dir:  *dir,
+				Load: pia.LoadProperties,
+			},
+			env: *name,
+		}
+		if base != nil {
+			src.envs.Base = *base
+		} else {
+			src.envs.Base = fs.Arg(0)
+		}
+		if *commands != "" {
+			exec, err := pia.LoadExecResolver(*commands)
+			if err != nil {
+				return sources{}, err
+			}
+			src.exec = exec
+		}
+		return src, nil
+	}
 }
 
-func properties(path string) (map[string]string, error) {
-	props := make(map[string]string)
-	src, err := os.ReadFile(path)
+// delegates returns the property sources available for interpolation, keyed by their context key.
+func (s sources) delegates(session *pia.Session) (map[string]pia.KeyResolver, error) {
+	props, err := s.envs.Properties(s.env)
 	if err != nil {
 		return nil, err
 	}
-	scn := bufio.NewScanner(strings.NewReader(string(src)))
-	for scn.Scan() {
-		line := scn.Text()
-		segments := strings.SplitN(line, "=", 2)
-		if len(segments) != 2 {
-			continue
+	d := map[string]pia.KeyResolver{
+		"env":     pia.EnvironmentResolver{},
+		"props":   pia.MapResolver(props),
+		"session": session,
+		"secret":  &pia.VaultResolver{Path: vaultPath(), Passphrase: passphrase},
+		"gen":     pia.NewGeneratorResolver(),
+	}
+	if s.exec != nil {
+		d["exec"] = s.exec
+	}
+	return d, nil
+}
+
+// loadResolver returns a resolver for interpolation using the property sources selected by the flags registered
+// through sourceFlags, along with the session it delegates to.
+func loadResolver(flags func() (sources, error)) (pia.KeyResolver, *pia.Session, error) {
+	src, err := flags()
+	if err != nil {
+		return nil, nil, err
+	}
+	session := pia.NewSession()
+	d, err := src.delegates(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pia.FallbackResolverDecorator{
+		Delegate: pia.DelegatingKeyResolver{
+			Delegates: d,
+		},
+	}, session, nil
+}
+
+// transaction parses the transaction file at path while interpolating it, and the files it refers to, using the
+// supplied resolver. Defaults files are looked for up to the working directory.
+func transaction(path string, resolver pia.KeyResolver) (*pia.Transaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return pia.ParseTransaction(
+		filepath.Dir(path),
+		f,
+		pia.WithResolver(resolver),
+		pia.WithRoot("."),
+	)
+}
+
+// parse parses args using fs while allowing flags to be interleaved with positional arguments, which the standard
+// library does not support on its own. The positional arguments are returned in the order they were given.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
 		}
-		props[segments[0]] = strings.TrimSpace(
-			strings.Trim(segments[1], "\""),
-		)
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
 	}
-	return props, nil
+}
+
+func usage(fs *flag.FlagSet, synopsis string) error {
+	return fmt.Errorf("usage: pia %s %s", fs.Name(), synopsis)
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ernilsson/pia"
+	"github.com/ernilsson/pia/cmd/pia/internal/tui"
+	"github.com/ernilsson/pia/squeak"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// run executes a single transaction, or a collection of transactions, without launching the terminal user interface
+// and writes the outcome to the standard output. Output from Squeak hooks is written to the standard error stream to
+// keep the outcome parsable.
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	flags := sourceFlags(fs, false)
+	format := fs.String("format", "text", "output format, either text or json")
+	junit := fs.String("junit", "", "path to write a JUnit XML report of the hook assertions to")
+	report := fs.String("report", "", "path to write a JSON report of the hook assertions to")
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<transaction|collection> [--props file] [--env name] [--env-dir dir] [--exec file] "+
+			"[--format text|json] [--junit file] [--report file]")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported output format: %s", *format)
+	}
+	resolver, session, err := loadResolver(flags)
+	if err != nil {
+		return err
+	}
+
+	path := positional[0]
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var results pia.Report
+	if info.IsDir() || filepath.Base(path) == pia.CollectionManifest {
+		results, err = runCollection(path, *format, resolver, session)
+	} else {
+		results, err = runTransaction(path, *format, resolver, session)
+	}
+	if *junit != "" {
+		if err := writeReport(*junit, results.WriteJUnit); err != nil {
+			return err
+		}
+	}
+	if *report != "" {
+		if err := writeReport(*report, results.WriteJSON); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func writeReport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f)
+}
+
+func runTransaction(path, format string, resolver pia.KeyResolver, session *pia.Session) (pia.Report, error) {
+	formatter := tui.ResponseFormatter
+	if format == "json" {
+		formatter = tui.JSONResponseFormatter
+	}
+	tx, err := transaction(path, resolver)
+	if err != nil {
+		failure, _ := pia.NewErrorResult(path, err)
+		return pia.Report{failure}, err
+	}
+	in := interpreter(tx.WD, session)
+	res, err := tx.Execute(in)
+	report := pia.Report(pia.NewTestResults(path, in.DrainResults()))
+	if failure, ok := pia.NewErrorResult(path, err); ok {
+		report = append(report, failure)
+	}
+	if res != nil {
+		defer res.Body.Close()
+		if err := formatter(os.Stdout, res); err != nil {
+			return report, err
+		}
+	}
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", path, err)
+	}
+	if report.Failed() > 0 {
+		return report, fmt.Errorf("%s: %d of %d tests failed", path, report.Failed(), len(report))
+	}
+	return report, nil
+}
+
+func runCollection(path, format string, resolver pia.KeyResolver, session *pia.Session) (pia.Report, error) {
+	formatter := tui.SummaryFormatter
+	if format == "json" {
+		formatter = tui.JSONSummaryFormatter
+	}
+	c, err := pia.LoadCollection(path)
+	if err != nil {
+		return nil, err
+	}
+	summary := c.Run(resolver, interpreter(c.WD, session), pia.WithRoot("."))
+	if err := formatter(os.Stdout, summary); err != nil {
+		return summary.Report(), err
+	}
+	if summary.Failed() > 0 {
+		return summary.Report(), fmt.Errorf("%s: %d of %d transactions failed", path, summary.Failed(), len(summary))
+	}
+	return summary.Report(), nil
+}
+
+// interpreter returns a Squeak interpreter writing to the standard error stream with the session declared.
+func interpreter(wd string, session *pia.Session) *squeak.Interpreter {
+	in := squeak.NewInterpreter(wd, os.Stderr)
+	in.Declare("session", squeak.NewSessionObject(session))
+	return in
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ernilsson/pia"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+// secret manages the secrets of the vault referenced through the secret context key. The operation is given by the
+// first argument.
+func secret(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: pia secret set|get|list|rm ...")
+	}
+	switch args[0] {
+	case "set":
+		return setSecret(args[1:])
+	case "get":
+		return getSecret(args[1:])
+	case "list":
+		return listSecrets(args[1:])
+	case "rm":
+		return removeSecret(args[1:])
+	default:
+		return fmt.Errorf("unsupported secret operation: %s", args[0])
+	}
+}
+
+func setSecret(args []string) error {
+	fs := flag.NewFlagSet("secret set", flag.ExitOnError)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 && len(positional) != 2 {
+		return usage(fs, "<name> [value]")
+	}
+	v, err := openVault(true)
+	if err != nil {
+		return err
+	}
+	var value string
+	if len(positional) == 2 {
+		value = positional[1]
+	} else if value, err = readSecret(positional[0]); err != nil {
+		return err
+	}
+	v.Set(positional[0], value)
+	return v.Save()
+}
+
+// readSecret reads the value of the named secret from the standard input, without echoing it if the standard input is
+// a terminal. Keeping values off the command line keeps them out of the shell history.
+func readSecret(name string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(src), "\r\n"), nil
+	}
+	return prompt(fmt.Sprintf("Value of %s: ", name))
+}
+
+func getSecret(args []string) error {
+	fs := flag.NewFlagSet("secret get", flag.ExitOnError)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<name>")
+	}
+	v, err := openVault(false)
+	if err != nil {
+		return err
+	}
+	value, err := v.Resolve(positional[0])
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(value)
+	return err
+}
+
+func listSecrets(args []string) error {
+	fs := flag.NewFlagSet("secret list", flag.ExitOnError)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usage(fs, "")
+	}
+	v, err := openVault(false)
+	if err != nil {
+		return err
+	}
+	for _, name := range v.Names() {
+		if _, err := fmt.Println(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeSecret(args []string) error {
+	fs := flag.NewFlagSet("secret rm", flag.ExitOnError)
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage(fs, "<name>")
+	}
+	v, err := openVault(false)
+	if err != nil {
+		return err
+	}
+	if !v.Remove(positional[0]) {
+		return fmt.Errorf("no secret named %s", positional[0])
+	}
+	return v.Save()
+}
+
+// openVault opens the vault of the working directory. Unless create is set the vault must already exist. When a vault
+// is created the passphrase is asked for twice to guard against typos.
+func openVault(create bool) (*pia.Vault, error) {
+	path := vaultPath()
+	_, err := os.Stat(path)
+	exists := err == nil
+	if !exists && !create {
+		return nil, fmt.Errorf("no vault at %s", path)
+	}
+	var p string
+	if exists {
+		p, err = passphrase()
+	} else {
+		p, err = newPassphrase()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pia.OpenVault(path, p)
+}
+
+// vaultPath returns the path of the vault, which is taken from PIA_VAULT when set.
+func vaultPath() string {
+	if path := os.Getenv("PIA_VAULT"); path != "" {
+		return path
+	}
+	return pia.DefaultVault
+}
+
+// passphrase returns the passphrase of the vault, which is taken from PIA_PASSPHRASE when set and asked for otherwise.
+func passphrase() (string, error) {
+	if p, ok := os.LookupEnv("PIA_PASSPHRASE"); ok {
+		return p, nil
+	}
+	return prompt("Passphrase: ")
+}
+
+func newPassphrase() (string, error) {
+	if p, ok := os.LookupEnv("PIA_PASSPHRASE"); ok {
+		return p, nil
+	}
+	p, err := prompt("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := prompt("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p != confirmation {
+		return "", errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+// prompt asks for a value on the terminal without echoing it.
+func prompt(message string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("cannot prompt without a terminal, set PIA_PASSPHRASE instead")
+	}
+	fmt.Fprint(os.Stderr, message)
+	defer fmt.Fprintln(os.Stderr)
+	p, err := term.ReadPassword(fd)
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
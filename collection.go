@@ -0,0 +1,234 @@
+package pia
+
+import (
+	"fmt"
+	"github.com/ernilsson/pia/squeak"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CollectionManifest is the name of the file which, when present in a directory, lists the transactions of the
+// collection represented by the directory.
+const CollectionManifest = "collection.yml"
+
+// ImportedVariables is the name of the property file that importers write the variables of an imported collection or
+// specification to.
+const ImportedVariables = "variables.properties"
+
+// FailurePolicy decides how a [pia.Collection] proceeds after one of its transactions has failed.
+type FailurePolicy string
+
+const (
+	// StopOnFailure skips all remaining transactions once a transaction has failed.
+	StopOnFailure FailurePolicy = "stop"
+	// ContinueOnFailure runs all transactions regardless of the outcome of previous transactions.
+	ContinueOnFailure FailurePolicy = "continue"
+)
+
+// collection represents the manifest of a Collection value in its textual YAML state.
+type collection struct {
+	Transactions []string      `yaml:"transactions"`
+	OnFailure    FailurePolicy `yaml:"on_failure,omitempty"`
+}
+
+// LoadCollection builds a Collection from path, which may either be a manifest file or a directory. If path is a
+// directory containing a manifest then the manifest is used, otherwise every YAML file in the directory is considered
+// part of the collection in lexical order.
+func LoadCollection(path string) (*Collection, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadManifest(path)
+	}
+	manifest := filepath.Join(path, CollectionManifest)
+	if _, err := os.Stat(manifest); err == nil {
+		return loadManifest(manifest)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	c := Collection{
+		WD:        path,
+		OnFailure: StopOnFailure,
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !IsTransactionFile(entry.Name()) {
+			continue
+		}
+		c.Transactions = append(c.Transactions, filepath.Join(path, entry.Name()))
+	}
+	slices.Sort(c.Transactions)
+	return &c, nil
+}
+
+func loadManifest(path string) (*Collection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cfg collection
+	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
+		return nil, err
+	}
+	c := Collection{
+		WD:        filepath.Dir(path),
+		OnFailure: cfg.OnFailure,
+	}
+	switch c.OnFailure {
+	case "":
+		c.OnFailure = StopOnFailure
+	case StopOnFailure, ContinueOnFailure:
+	default:
+		return nil, fmt.Errorf("%s: unrecognized failure policy '%s'", path, c.OnFailure)
+	}
+	for _, tx := range cfg.Transactions {
+		if !filepath.IsAbs(tx) {
+			tx = filepath.Join(c.WD, tx)
+		}
+		c.Transactions = append(c.Transactions, tx)
+	}
+	return &c, nil
+}
+
+// IsTransactionFile reports whether the file name denotes a transaction, that is a YAML file which is neither a
+// collection manifest nor a defaults file.
+func IsTransactionFile(name string) bool {
+	if name == CollectionManifest || name == DefaultsFile {
+		return false
+	}
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+}
+
+// Collection is an ordered list of transaction files which are executed one after the other, sharing the same
+// interpreter and thus the same session.
+type Collection struct {
+	WD           string
+	Transactions []string
+	OnFailure    FailurePolicy
+}
+
+// Run executes the transactions of the collection in order. Each transaction file is interpolated using the supplied
+// resolver as it is parsed, and all hooks are run by the supplied interpreter. Transactions that are skipped due to
+// the failure policy of the collection are part of the returned summary as skipped results. Transactions are parsed
+// using the supplied options, along with [pia.WithResolver] for the supplied resolver.
+func (c *Collection) Run(resolver KeyResolver, in *squeak.Interpreter, opts ...ParseOption) Summary {
+	summary := make(Summary, 0, len(c.Transactions))
+	opts = append(slices.Clip(opts), WithResolver(resolver))
+	for i, path := range c.Transactions {
+		result := c.execute(resolver, in, path, opts)
+		summary = append(summary, result)
+		if !result.Passed() && c.OnFailure == StopOnFailure {
+			for _, skipped := range c.Transactions[i+1:] {
+				summary = append(summary, Result{File: skipped, Skipped: true})
+			}
+			break
+		}
+	}
+	return summary
+}
+
+func (c *Collection) execute(
+	resolver KeyResolver,
+	in *squeak.Interpreter,
+	path string,
+	opts []ParseOption,
+) (result Result) {
+	result.File = path
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+	f, err := os.Open(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer f.Close()
+	Reset(resolver)
+	tx, err := ParseTransaction(filepath.Dir(path), f, opts...)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Method = tx.Method
+	result.Target = tx.URL.Target
+	res, err := tx.Execute(in)
+	result.Tests = NewTestResults(path, in.DrainResults())
+	if res != nil {
+		result.Status = res.StatusCode
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+	result.Err = err
+	return result
+}
+
+// Result is the outcome of executing a single transaction as part of a collection.
+type Result struct {
+	File     string
+	Method   string
+	Target   string
+	Status   int
+	Duration time.Duration
+	Err      error
+	Tests    []TestResult
+	// Skipped is set for transactions that were not executed due to the failure policy of the collection.
+	Skipped bool
+}
+
+// Passed reports whether the transaction was executed without any errors and without any failed tests.
+func (r Result) Passed() bool {
+	return !r.Skipped && r.Err == nil && Report(r.Tests).Failed() == 0
+}
+
+// Summary holds the results of a collection run in the order the transactions were executed, followed by the results
+// of any skipped transactions.
+type Summary []Result
+
+// Failed returns the number of results that did not pass, not counting skipped results.
+func (s Summary) Failed() int {
+	n := 0
+	for _, r := range s {
+		if !r.Passed() && !r.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// Skipped returns the number of results of transactions that were skipped.
+func (s Summary) Skipped() int {
+	n := 0
+	for _, r := range s {
+		if r.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// Report gathers the test results of all transactions in the summary. Transactions that failed for reasons other than a
+// failed assertion are included as failed test results as well, and skipped transactions as skipped test results.
+func (s Summary) Report() Report {
+	var report Report
+	for _, r := range s {
+		if r.Skipped {
+			report = append(report, TestResult{Name: "execute", File: r.File, Skipped: true})
+			continue
+		}
+		report = append(report, r.Tests...)
+		if res, ok := NewErrorResult(r.File, r.Err); ok {
+			report = append(report, res)
+		}
+	}
+	return report
+}
@@ -0,0 +1,174 @@
+package pia_test
+
+import (
+	"fmt"
+	"github.com/ernilsson/pia"
+	"github.com/ernilsson/pia/squeak"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollection_Run(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			_, _ = w.Write([]byte(`{"token": "abc"}`))
+		case "/protected":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	write := func(t *testing.T, dir, name, content string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666)
+		assert.Nil(t, err)
+	}
+	login := fmt.Sprintf(`
+method: POST
+url:
+  target: %s/login
+hooks:
+  after:
+    inline: session.set("token", response.json().token);
+`, srv.URL)
+	protected := fmt.Sprintf(`
+method: GET
+url:
+  target: %s/protected
+headers:
+  Authorization: Bearer ${session:token}
+hooks:
+  after:
+    inline: assert(response.status_code == 200, "expected 200");
+`, srv.URL)
+	missing := fmt.Sprintf(`
+method: GET
+url:
+  target: %s/missing
+hooks:
+  after:
+    inline: assert(response.status_code == 200, "expected 200");
+`, srv.URL)
+
+	run := func(t *testing.T, path string) pia.Summary {
+		c, err := pia.LoadCollection(path)
+		assert.Nil(t, err)
+		session := pia.NewSession()
+		in := squeak.NewInterpreter(c.WD, io.Discard)
+		in.Declare("session", squeak.NewSessionObject(session))
+		return c.Run(pia.FallbackResolverDecorator{
+			Delegate: pia.DelegatingKeyResolver{
+				Delegates: map[string]pia.KeyResolver{"session": session},
+			},
+		}, in)
+	}
+
+	t.Run("directory in lexical order sharing session", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "01-login.yml", login)
+		write(t, dir, "02-protected.yml", protected)
+		write(t, dir, "README.md", "not a transaction")
+		summary := run(t, dir)
+		assert.Len(t, summary, 2)
+		assert.Equal(t, 0, summary.Failed())
+		assert.Equal(t, filepath.Join(dir, "02-protected.yml"), summary[1].File)
+		assert.Equal(t, http.StatusOK, summary[1].Status)
+	})
+
+	t.Run("directory with defaults", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, pia.DefaultsFile, fmt.Sprintf(`
+url:
+  target: %s
+headers:
+  Authorization: Bearer ${session:token|}
+`, srv.URL))
+		write(t, dir, "01-login.yml", strings.Replace(login, srv.URL, "", 1))
+		write(t, dir, "02-protected.yml", `
+method: GET
+url:
+  target: /protected
+hooks:
+  after:
+    inline: assert(response.status_code == 200, "expected 200");
+`)
+		summary := run(t, dir)
+		assert.Len(t, summary, 2)
+		assert.Equal(t, 0, summary.Failed())
+		assert.Equal(t, srv.URL+"/protected", summary[1].Target)
+	})
+
+	t.Run("stop on failure", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "01-missing.yml", missing)
+		write(t, dir, "02-login.yml", login)
+		summary := run(t, dir)
+		assert.Len(t, summary, 2)
+		assert.Equal(t, 1, summary.Failed())
+		assert.Equal(t, 1, summary.Skipped())
+		assert.ErrorIs(t, summary[0].Err, squeak.ErrFailedAssertion)
+		assert.Equal(t, http.StatusNotFound, summary[0].Status)
+		assert.Equal(t, pia.Result{File: filepath.Join(dir, "02-login.yml"), Skipped: true}, summary[1])
+		assert.False(t, summary[1].Passed())
+		assert.Equal(t, pia.Report{
+			{
+				Name:    "expected 200",
+				File:    filepath.Join(dir, "01-missing.yml"),
+				Message: "runtime error: assertion failed: expected 200",
+			},
+			{
+				Name:    "execute",
+				File:    filepath.Join(dir, "02-login.yml"),
+				Skipped: true,
+			},
+		}, summary.Report())
+	})
+
+	t.Run("manifest continuing on failure", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "login.yml", login)
+		write(t, dir, "missing.yml", missing)
+		write(t, dir, "protected.yml", protected)
+		write(t, dir, pia.CollectionManifest, `
+on_failure: continue
+transactions:
+  - missing.yml
+  - login.yml
+  - protected.yml
+`)
+		summary := run(t, dir)
+		assert.Len(t, summary, 3)
+		assert.Equal(t, 1, summary.Failed())
+		assert.False(t, summary[0].Passed())
+		assert.True(t, summary[2].Passed())
+	})
+
+	t.Run("hook syntax error", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "broken.yml", strings.Replace(login, "inline: ", "inline: let = ; ", 1))
+		summary := run(t, dir)
+		assert.Len(t, summary, 1)
+		report := summary.Report()
+		assert.Len(t, report, 1)
+		assert.Equal(t, "execute", report[0].Name)
+		assert.False(t, report[0].Passed)
+		assert.Contains(t, report[0].Message, "syntax error")
+	})
+
+	t.Run("unrecognized failure policy", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, pia.CollectionManifest, `on_failure: retry`)
+		_, err := pia.LoadCollection(dir)
+		assert.NotNil(t, err)
+	})
+}
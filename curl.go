@@ -0,0 +1,561 @@
+package pia
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Curl returns a curl command line which sends the same request as the Transaction, without running any of its hooks.
+// Bodies that reference a file are referenced by the command line as well rather than being inlined, unless the file
+// has been interpolated. Since the body of the Transaction is consumed, a Transaction should not be executed after it
+// has been exported.
+func (tx *Transaction) Curl() (string, error) {
+	req, err := tx.Request()
+	if err != nil {
+		return "", err
+	}
+	lines := []string{"curl", "-X " + quote(req.Method), quote(req.URL.String())}
+	multipart := tx.body != nil && len(tx.body.Multipart) > 0 && tx.body.tree() == nil
+	names := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	for _, k := range names {
+		if multipart && k == "Content-Type" && strings.HasPrefix(req.Header.Get(k), "multipart/form-data") {
+			// Curl generates its own boundary for multipart bodies, so the header must be left for curl to set.
+			continue
+		}
+		for _, v := range req.Header.Values(k) {
+			lines = append(lines, "-H "+quote(k+": "+v))
+		}
+	}
+	body, err := tx.curlBody(req.Body)
+	if err != nil {
+		return "", err
+	}
+	lines = append(lines, body...)
+	return strings.Join(lines, " \\\n  "), nil
+}
+
+// curlBody returns the command line options which make curl send the body of the Transaction, one option per item.
+func (tx *Transaction) curlBody(r io.Reader) ([]string, error) {
+	path := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(tx.WD, p)
+	}
+	// Bodies given verbatim or as JSON are read from the request, all other types of bodies are rebuilt from their
+	// configuration to let curl handle the encoding.
+	if tx.body != nil && tx.body.tree() == nil {
+		var opts []string
+		switch {
+		case len(tx.body.Multipart) > 0:
+			for _, p := range tx.body.Multipart {
+				if p.File == "" {
+					opts = append(opts, "--form-string "+quote(p.Name+"="+p.Value))
+					continue
+				}
+				field := p.Name + "=@" + path(p.File)
+				if p.Filename != "" {
+					field += ";filename=" + p.Filename
+				}
+				if p.ContentType != "" {
+					field += ";type=" + p.ContentType
+				}
+				opts = append(opts, "-F "+quote(field))
+			}
+			return opts, nil
+		case len(tx.body.Form) > 0:
+			keys := make([]string, 0, len(tx.body.Form))
+			for k := range tx.body.Form {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				opts = append(opts, "--data-urlencode "+quote(k+"="+tx.body.Form[k]))
+			}
+			return opts, nil
+		case tx.body.Inline == "" && tx.body.File != "" && !tx.body.resolved:
+			return []string{"--data-binary " + quote("@"+path(tx.body.File))}, nil
+		}
+	}
+	if r == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return []string{"--data-raw " + quote(string(data))}, nil
+}
+
+// quote returns s quoted for a POSIX shell. Strings consisting only of characters without special meaning to the shell
+// are returned as is.
+func quote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ImportCurl parses a curl command line and writes an equivalent transaction, in its textual YAML state, to w. Only the
+// options that affect the request itself are translated, options that solely affect the output of curl are ignored and
+// options that cannot be translated result in an error.
+func ImportCurl(w io.Writer, cmd string) error {
+	args, err := split(cmd)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return errors.New("not a curl command")
+	}
+	cfg, err := parseCurl(args[1:])
+	if err != nil {
+		return err
+	}
+	return encodeYAML(w, cfg)
+}
+
+// curlFlags are the options of curl which do not take an argument and have no bearing on the imported transaction.
+var curlFlags = []string{
+	"-s", "--silent", "-S", "--show-error", "-v", "--verbose", "-i", "--include", "--compressed", "-L", "--location",
+	"-f", "--fail", "-#", "--progress-bar", "-N", "--no-buffer", "--http1.1", "--http2", "-g", "--globoff",
+}
+
+// curlSwitches are the short options of curl which do not take an argument.
+var curlSwitches = []string{"-s", "-S", "-v", "-i", "-L", "-f", "-#", "-N", "-g", "-G", "-I", "-k"}
+
+// curlIgnored are the options of curl which take an argument but have no bearing on the imported transaction.
+var curlIgnored = []string{
+	"-o", "--output", "-w", "--write-out", "-c", "--cookie-jar", "--connect-timeout", "--retry", "-D",
+	"--dump-header",
+}
+
+func parseCurl(args []string) (*transaction, error) {
+	var (
+		cfg     transaction
+		target  string
+		data    []string
+		form    = make(map[string]string)
+		get     bool
+		head    bool
+		headers = make(map[string]string)
+		hc      client
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			target = arg
+			continue
+		}
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 && slices.Contains(curlSwitches, arg[:2]) {
+			// Short options without arguments may be bundled, as in -sSL, in which case they are handled one by one.
+			args = slices.Insert(args, i+1, "-"+arg[2:])
+			arg = arg[:2]
+		}
+		name, value, inline := strings.Cut(arg, "=")
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// Short options may be immediately followed by their argument, as in -XPOST.
+			name, value, inline = arg[:2], arg[2:], true
+		}
+		if slices.Contains(curlFlags, arg) {
+			continue
+		}
+		next := func() (string, error) {
+			if inline {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl option %s requires an argument", name)
+			}
+			i++
+			return args[i], nil
+		}
+		switch name {
+		case "-X", "--request", "-H", "--header", "-d", "--data", "--data-raw", "--data-binary", "--data-ascii",
+			"--data-urlencode", "-F", "--form", "--form-string", "-u", "--user", "--url", "-A", "--user-agent",
+			"-e", "--referer", "-b", "--cookie", "-x", "--proxy", "-m", "--max-time", "--cacert", "-E", "--cert",
+			"--key":
+		default:
+			if slices.Contains(curlIgnored, name) {
+				if _, err := next(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			switch arg {
+			case "-G", "--get":
+				get = true
+			case "-I", "--head":
+				head = true
+			case "-k", "--insecure":
+				hc.InsecureSkipVerify = true
+			default:
+				return nil, fmt.Errorf("unsupported curl option: %s", arg)
+			}
+			continue
+		}
+		value, err := next()
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "-X", "--request":
+			cfg.Method = value
+		case "-H", "--header":
+			k, v, ok := strings.Cut(value, ":")
+			if !ok {
+				return nil, fmt.Errorf("malformed header: %s", value)
+			}
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if name != "--data-raw" && strings.HasPrefix(value, "@") {
+				if len(data) > 0 || cfg.Body.File != "" {
+					return nil, errors.New("a file body cannot be combined with other data")
+				}
+				cfg.Body.File = value[1:]
+				continue
+			}
+			data = append(data, value)
+		case "--data-urlencode":
+			k, v, ok := strings.Cut(value, "=")
+			if !ok || k == "" {
+				return nil, fmt.Errorf("unsupported --data-urlencode argument: %s", value)
+			}
+			form[k] = v
+		case "-F", "--form", "--form-string":
+			p, err := parseCurlPart(value, name == "--form-string")
+			if err != nil {
+				return nil, err
+			}
+			cfg.Body.Multipart = append(cfg.Body.Multipart, p)
+		case "-u", "--user":
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(value))
+		case "--url":
+			target = value
+		case "-A", "--user-agent":
+			headers["User-Agent"] = value
+		case "-e", "--referer":
+			headers["Referer"] = value
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				return nil, fmt.Errorf("reading cookies from a file is not supported: %s", value)
+			}
+			headers["Cookie"] = value
+		case "-x", "--proxy":
+			hc.Proxy = value
+		case "-m", "--max-time":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --max-time: %w", err)
+			}
+			hc.Timeout = time.Duration(seconds * float64(time.Second))
+		case "--cacert":
+			hc.CAFile = value
+		case "-E", "--cert":
+			hc.CertFile = value
+		case "--key":
+			hc.KeyFile = value
+		}
+	}
+	if target == "" {
+		return nil, errors.New("curl command has no url")
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	u.RawQuery = ""
+	cfg.URL.Target = u.String()
+	if get {
+		// With --get all data is sent as part of the query instead of as a body.
+		extra, err := url.ParseQuery(strings.Join(data, "&"))
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range extra {
+			query[k] = append(query[k], vs...)
+		}
+		for k, v := range form {
+			query.Add(k, v)
+		}
+		data, form = nil, map[string]string{}
+	}
+	if len(query) > 0 {
+		cfg.URL.Query = make(map[string]string, len(query))
+		for k, vs := range query {
+			if len(vs) > 1 {
+				return nil, fmt.Errorf("query parameter %s is given more than once", k)
+			}
+			cfg.URL.Query[k] = vs[0]
+		}
+	}
+	contentType := ""
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = v
+		}
+	}
+	switch {
+	case len(cfg.Body.Multipart) > 0:
+		if len(data) > 0 || len(form) > 0 || cfg.Body.File != "" {
+			return nil, errors.New("multipart bodies cannot be combined with other data")
+		}
+	case len(data) > 0 || len(form) > 0:
+		if cfg.Body.File != "" {
+			return nil, errors.New("a file body cannot be combined with other data")
+		}
+		// Curl sends data as application/x-www-form-urlencoded unless told otherwise, in which case the data can be
+		// represented as a form as long as it consists of properly encoded fields.
+		values, err := url.ParseQuery(strings.Join(data, "&"))
+		isForm := err == nil && (contentType == "" || strings.HasPrefix(contentType, "application/x-www-form-urlencoded"))
+		for k, vs := range values {
+			if k == "" || len(vs) > 1 || form[k] != "" {
+				isForm = false
+			}
+		}
+		if isForm {
+			for k, vs := range values {
+				form[k] = vs[0]
+			}
+			cfg.Body.Form = form
+			if contentType == "" {
+				headers["Content-Type"] = "application/x-www-form-urlencoded"
+			}
+			break
+		}
+		if len(form) > 0 {
+			encoded := make(url.Values, len(form))
+			for k, v := range form {
+				encoded.Set(k, v)
+			}
+			data = append(data, encoded.Encode())
+		}
+		cfg.Body.Inline = strings.Join(data, "&")
+		if contentType == "" {
+			headers["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+	}
+	switch {
+	case cfg.Method != "":
+	case head:
+		cfg.Method = http.MethodHead
+	case cfg.Body.Inline != "" || cfg.Body.File != "" || len(cfg.Body.Form) > 0 || len(cfg.Body.Multipart) > 0:
+		cfg.Method = http.MethodPost
+	default:
+		cfg.Method = http.MethodGet
+	}
+	if len(headers) > 0 {
+		cfg.Headers = headers
+	}
+	if hc != (client{}) {
+		cfg.Client = &hc
+	}
+	cfg.escape()
+	return &cfg, nil
+}
+
+// escape escapes the substitution points within the values of the configuration, which is needed for configurations
+// taken verbatim from other tools since a "${" of theirs is not meant to be interpolated.
+func (cfg *transaction) escape() {
+	escapeMap := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		escaped := make(map[string]string, len(m))
+		for k, v := range m {
+			escaped[escapePlaceholders(k)] = escapePlaceholders(v)
+		}
+		return escaped
+	}
+	cfg.Method = escapePlaceholders(cfg.Method)
+	cfg.URL.Target = escapePlaceholders(cfg.URL.Target)
+	cfg.URL.Query = escapeMap(cfg.URL.Query)
+	cfg.Headers = escapeMap(cfg.Headers)
+	cfg.Body.Inline = escapePlaceholders(cfg.Body.Inline)
+	cfg.Body.File = escapePlaceholders(cfg.Body.File)
+	cfg.Body.Form = escapeMap(cfg.Body.Form)
+	for i, p := range cfg.Body.Multipart {
+		cfg.Body.Multipart[i] = part{
+			Name:        escapePlaceholders(p.Name),
+			Value:       escapePlaceholders(p.Value),
+			File:        escapePlaceholders(p.File),
+			Filename:    escapePlaceholders(p.Filename),
+			ContentType: escapePlaceholders(p.ContentType),
+		}
+	}
+	if c := cfg.Client; c != nil {
+		c.Proxy = escapePlaceholders(c.Proxy)
+		c.CAFile = escapePlaceholders(c.CAFile)
+		c.CertFile = escapePlaceholders(c.CertFile)
+		c.KeyFile = escapePlaceholders(c.KeyFile)
+	}
+}
+
+// parseCurlPart parses the argument of a -F option. Unless literal is set, a value starting with @ references a file
+// and may be followed by ;filename= and ;type= attributes.
+func parseCurlPart(arg string, literal bool) (part, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return part{}, fmt.Errorf("malformed form field: %s", arg)
+	}
+	p := part{Name: name}
+	switch {
+	case literal:
+		p.Value = value
+	case strings.HasPrefix(value, "@"):
+		attrs := strings.Split(value[1:], ";")
+		p.File = attrs[0]
+		for _, attr := range attrs[1:] {
+			k, v, _ := strings.Cut(attr, "=")
+			switch k {
+			case "filename":
+				p.Filename = strings.Trim(v, `"`)
+			case "type":
+				p.ContentType = v
+			default:
+				return part{}, fmt.Errorf("unsupported form field attribute: %s", attr)
+			}
+		}
+	case strings.HasPrefix(value, "<"):
+		return part{}, fmt.Errorf("form fields read from a file are not supported: %s", arg)
+	default:
+		p.Value = value
+	}
+	return p, nil
+}
+
+// split splits a command line into its arguments the way a POSIX shell would, honoring single quotes, double quotes,
+// backslash escapes, line continuations and the $'...' quoting that browsers use when copying requests as curl.
+func split(cmd string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		started bool
+	)
+	runes := []rune(cmd)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, errors.New("unterminated escape sequence")
+			}
+			i++
+			if runes[i] == '\n' {
+				continue
+			}
+			current.WriteRune(runes[i])
+			started = true
+		case r == '\'':
+			end := slices.Index(runes[i+1:], '\'')
+			if end == -1 {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(string(runes[i+1 : i+1+end]))
+			i += end + 1
+			started = true
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+			started = true
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			n, err := ansiC(runes[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			started = true
+		case unicode.IsSpace(r):
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ansiC decodes the body of a $'...' string, up to and including the closing quote, into b. It returns the number of
+// runes consumed.
+func ansiC(runes []rune, b *strings.Builder) (int, error) {
+	escapes := map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', 'a': '\a', 'b': '\b', 'f': '\f', 'v': '\v', 'e': 0x1b}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\'' {
+			return i + 1, nil
+		}
+		if r != '\\' || i+1 >= len(runes) {
+			b.WriteRune(r)
+			continue
+		}
+		i++
+		if e, ok := escapes[runes[i]]; ok {
+			b.WriteRune(e)
+			continue
+		}
+		digits := 0
+		switch runes[i] {
+		case 'x':
+			digits = 2
+		case 'u':
+			digits = 4
+		case 'U':
+			digits = 8
+		default:
+			b.WriteRune(runes[i])
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && end <= i+digits && strings.ContainsRune("0123456789abcdefABCDEF", runes[end]) {
+			end++
+		}
+		code, err := strconv.ParseUint(string(runes[i+1:end]), 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid escape sequence: \\%s", string(runes[i:end]))
+		}
+		if runes[i] == 'x' {
+			b.WriteByte(byte(code))
+		} else {
+			b.WriteRune(rune(code))
+		}
+		i = end - 1
+	}
+	return 0, errors.New("unterminated $' quote")
+}
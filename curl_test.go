@@ -0,0 +1,288 @@
+package pia_test
+
+import (
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransaction_Curl(t *testing.T) {
+	tests := []struct {
+		name     string
+		tx       string
+		expected string
+	}{
+		{
+			name: "query and headers",
+			tx: `
+method: GET
+url:
+  target: https://example.com/users
+  query:
+    name: John Doe
+headers:
+  X-Trace: abc
+  Authorization: Bearer abc
+`,
+			expected: `curl \
+  -X GET \
+  'https://example.com/users?name=John+Doe' \
+  -H 'Authorization: Bearer abc' \
+  -H 'X-Trace: abc'`,
+		},
+		{
+			name: "inline body",
+			tx: `
+method: POST
+url:
+  target: https://example.com/users
+body:
+  inline: '{"name": "O''Brien"}'
+`,
+			expected: `curl \
+  -X POST \
+  https://example.com/users \
+  --data-raw '{"name": "O'\''Brien"}'`,
+		},
+		{
+			name: "file body",
+			tx: `
+method: PUT
+url:
+  target: https://example.com/users/1
+body:
+  file: user.json
+`,
+			expected: `curl \
+  -X PUT \
+  https://example.com/users/1 \
+  --data-binary @$WD/user.json`,
+		},
+		{
+			name: "form body",
+			tx: `
+method: POST
+url:
+  target: https://example.com/login
+body:
+  form:
+    username: admin
+    password: p@ss word
+`,
+			expected: `curl \
+  -X POST \
+  https://example.com/login \
+  --data-urlencode 'password=p@ss word' \
+  --data-urlencode username=admin`,
+		},
+		{
+			name: "multipart body",
+			tx: `
+method: POST
+url:
+  target: https://example.com/upload
+body:
+  multipart:
+    - name: description
+      value: '@not a file'
+    - name: user
+      file: user.json
+      filename: me.json
+      content_type: application/json
+`,
+			expected: `curl \
+  -X POST \
+  https://example.com/upload \
+  --form-string 'description=@not a file' \
+  -F 'user=@$WD/user.json;filename=me.json;type=application/json'`,
+		},
+		{
+			name: "json body",
+			tx: `
+method: POST
+url:
+  target: https://example.com/users
+body:
+  json:
+    name: admin
+`,
+			expected: `curl \
+  -X POST \
+  https://example.com/users \
+  -H 'Content-Type: application/json' \
+  --data-raw '{"name":"admin"}'`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wd := t.TempDir()
+			assert.Nil(t, os.WriteFile(filepath.Join(wd, "user.json"), []byte(`{}`), 0666))
+			tx, err := pia.ParseTransaction(wd, strings.NewReader(test.tx))
+			assert.Nil(t, err)
+			cmd, err := tx.Curl()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.ReplaceAll(test.expected, "$WD", wd), cmd)
+		})
+	}
+}
+
+func TestImportCurl(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		expected string
+	}{
+		{
+			name: "query, headers and user",
+			cmd: `curl 'https://example.com/users?name=John%20Doe' \
+  -H 'Accept: application/json' -u admin:secret --compressed`,
+			expected: `method: GET
+url:
+  target: https://example.com/users
+  query:
+    name: John Doe
+headers:
+  Accept: application/json
+  Authorization: Basic YWRtaW46c2VjcmV0
+`,
+		},
+		{
+			name: "literal placeholders",
+			cmd: `curl 'https://example.com/search?q=${term}' -H 'X-Template: ${name}' ` +
+				`-H 'Content-Type: text/plain' --data-raw 'echo ${HOME} $${USER}'`,
+			expected: `method: POST
+url:
+  target: https://example.com/search
+  query:
+    q: $${term}
+headers:
+  Content-Type: text/plain
+  X-Template: $${name}
+body:
+  inline: echo $${HOME} $$${USER}
+`,
+		},
+		{
+			name: "literal placeholders in form fields",
+			cmd:  `curl https://example.com/scripts -F 'script=echo ${PATH}'`,
+			expected: `method: POST
+url:
+  target: https://example.com/scripts
+body:
+  multipart:
+    - name: script
+      value: echo $${PATH}
+`,
+		},
+		{
+			name: "inline body",
+			cmd:  `curl -X PUT --url https://example.com/users/1 -H "Content-Type: application/json" --data-raw $'{"name":"O\'Brien"}'`,
+			expected: `method: PUT
+url:
+  target: https://example.com/users/1
+headers:
+  Content-Type: application/json
+body:
+  inline: '{"name":"O''Brien"}'
+`,
+		},
+		{
+			name: "form body",
+			cmd:  `curl https://example.com/login -d username=admin --data-urlencode 'password=p@ss word'`,
+			expected: `method: POST
+url:
+  target: https://example.com/login
+headers:
+  Content-Type: application/x-www-form-urlencoded
+body:
+  form:
+    password: p@ss word
+    username: admin
+`,
+		},
+		{
+			name: "file body",
+			cmd:  `curl -sSL -XPOST https://example.com/users --data-binary @user.json`,
+			expected: `method: POST
+url:
+  target: https://example.com/users
+body:
+  file: user.json
+`,
+		},
+		{
+			name: "multipart body",
+			cmd:  `curl https://example.com/upload -F description=avatar -F 'user=@user.json;type=application/json'`,
+			expected: `method: POST
+url:
+  target: https://example.com/upload
+body:
+  multipart:
+    - name: description
+      value: avatar
+    - name: user
+      file: user.json
+      content_type: application/json
+`,
+		},
+		{
+			name: "get with data",
+			cmd:  `curl -G https://example.com/search -d q=pia -k`,
+			expected: `method: GET
+url:
+  target: https://example.com/search
+  query:
+    q: pia
+client:
+  insecure_skip_verify: true
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			assert.Nil(t, pia.ImportCurl(&b, test.cmd))
+			assert.Equal(t, test.expected, b.String())
+			wd := t.TempDir()
+			assert.Nil(t, os.WriteFile(filepath.Join(wd, "user.json"), []byte(`{}`), 0666))
+			_, err := pia.ParseTransaction(wd, strings.NewReader(b.String()))
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestImportCurl_LiteralPlaceholders(t *testing.T) {
+	var b strings.Builder
+	cmd := `curl 'https://example.com/search?q=${term}' -H 'X-Template: ${name}' -H 'Content-Type: text/plain' ` +
+		`--data-raw 'echo ${HOME} $${USER}'`
+	assert.Nil(t, pia.ImportCurl(&b, cmd))
+	tx, err := pia.ParseTransaction("", strings.NewReader(b.String()), pia.WithResolver(pia.MapResolver{}))
+	assert.Nil(t, err)
+	assert.Equal(t, "${term}", tx.URL.Query["q"])
+	assert.Equal(t, "${name}", tx.Headers["X-Template"])
+	body, err := io.ReadAll(tx.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "echo ${HOME} $${USER}", string(body))
+}
+
+func TestImportCurl_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+	}{
+		{name: "not curl", cmd: `wget https://example.com`},
+		{name: "no url", cmd: `curl -X GET`},
+		{name: "unsupported option", cmd: `curl --unknown https://example.com`},
+		{name: "missing argument", cmd: `curl https://example.com -H`},
+		{name: "unterminated quote", cmd: `curl 'https://example.com`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.NotNil(t, pia.ImportCurl(io.Discard, test.cmd))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package pia
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// EnvironmentsDir is the conventional directory holding the property files of named environments.
+const EnvironmentsDir = "envs"
+
+// ErrEnvironmentNotFound is returned when a named environment has no property file.
+var ErrEnvironmentNotFound = errors.New("environment not found")
+
+// Environments is a set of named environments layered over a shared base property file. Every environment is a
+// property file named after the environment within Dir, such as envs/prod.properties or envs/prod.yml for the
+// environment prod, whose properties take precedence over the properties of the base file. Environment files may have
+// any of the [pia.PropertyFileExtensions]. Both the base file and the directory are optional.
+type Environments struct {
+	Base string
+	Dir  string
+	// Load parses the property file at the supplied path.
+	Load func(path string) (map[string]string, error)
+}
+
+// Names returns the names of the available environments in lexical order. A missing directory holds no environments.
+func (e Environments) Names() ([]string, error) {
+	if e.Dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(e.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if entry.IsDir() || name == "" || !slices.Contains(PropertyFileExtensions, ext) {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// Properties returns the properties of the named environment layered over the properties of the base file. An empty
+// name denotes the base file on its own.
+func (e Environments) Properties(name string) (map[string]string, error) {
+	props := make(map[string]string)
+	if e.Base != "" {
+		base, err := e.Load(e.Base)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(props, base)
+	}
+	if name == "" {
+		return props, nil
+	}
+	path, err := e.file(name)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := e.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(props, overrides)
+	return props, nil
+}
+
+// file returns the path of the property file of the named environment. Should the environment have several files,
+// the extension listed first among the [pia.PropertyFileExtensions] wins.
+func (e Environments) file(name string) (string, error) {
+	for _, ext := range PropertyFileExtensions {
+		path := filepath.Join(e.Dir, name+ext)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrEnvironmentNotFound, name)
+}
@@ -0,0 +1,66 @@
+package pia_test
+
+import (
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvironments(t *testing.T) {
+	wd := t.TempDir()
+	assert.Nil(t, os.Mkdir(filepath.Join(wd, pia.EnvironmentsDir), 0777))
+	files := map[string]string{
+		"base.properties":      "host=localhost\nuser=admin\n",
+		"envs/prod.properties": "host=example.com\n",
+		"envs/dev.properties":  "host=dev.example.com\n",
+		"envs/dev.yml":         "host=ignored\n",
+		"envs/local.env":       "host=127.0.0.1\n",
+		"envs/staging.json":    "host=staging.example.com\n",
+		"envs/qa.yaml":         "host=qa.example.com\n",
+		"envs/notes.txt":       "not an environment\n",
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, name), []byte(content), 0666))
+	}
+	envs := pia.Environments{
+		Base: filepath.Join(wd, "base.properties"),
+		Dir:  filepath.Join(wd, pia.EnvironmentsDir),
+		Load: func(path string) (map[string]string, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			props := make(map[string]string)
+			for _, line := range strings.Fields(string(data)) {
+				k, v, _ := strings.Cut(line, "=")
+				props[k] = v
+			}
+			return props, nil
+		},
+	}
+	names, err := envs.Names()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dev", "local", "prod", "qa", "staging"}, names)
+
+	props, err := envs.Properties("")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"host": "localhost", "user": "admin"}, props)
+	props, err = envs.Properties("prod")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"host": "example.com", "user": "admin"}, props)
+	props, err = envs.Properties("dev")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev.example.com", props["host"])
+	props, err = envs.Properties("staging")
+	assert.Nil(t, err)
+	assert.Equal(t, "staging.example.com", props["host"])
+	_, err = envs.Properties("uat")
+	assert.ErrorIs(t, err, pia.ErrEnvironmentNotFound)
+
+	names, err = pia.Environments{Dir: filepath.Join(wd, "missing")}.Names()
+	assert.Nil(t, err)
+	assert.Empty(t, names)
+}
@@ -0,0 +1,142 @@
+package pia
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCommandTimeout is how long a command may run unless its definition says otherwise.
+const DefaultCommandTimeout = 30 * time.Second
+
+// Command is the definition of a command whose output is used as a property. Run is executed by the shell of the
+// system. The output is reused until TTL has passed, which means that it is never reused if no TTL is given, and the
+// command is killed if it runs for longer than Timeout.
+type Command struct {
+	Run     string        `yaml:"run"`
+	TTL     time.Duration `yaml:"ttl,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// ParseCommands parses command definitions keyed by their names, such as
+//
+//	token:
+//	  run: gcloud auth print-access-token
+//	  ttl: 30m
+//	  timeout: 10s
+func ParseCommands(r io.Reader) (map[string]Command, error) {
+	commands := make(map[string]Command)
+	if err := yaml.NewDecoder(r).Decode(&commands); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for name, cmd := range commands {
+		if strings.TrimSpace(cmd.Run) == "" {
+			return nil, fmt.Errorf("command %s has nothing to run", name)
+		}
+	}
+	return commands, nil
+}
+
+// LoadExecResolver returns an [pia.ExecResolver] for the commands defined in the file at path. The commands are run
+// in the directory of the file.
+func LoadExecResolver(path string) (*ExecResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	commands, err := ParseCommands(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewExecResolver(filepath.Dir(path), commands), nil
+}
+
+// NewExecResolver returns an [pia.ExecResolver] for the supplied commands, which are run in dir.
+func NewExecResolver(dir string, commands map[string]Command) *ExecResolver {
+	return &ExecResolver{
+		dir:      dir,
+		commands: commands,
+		cache:    make(map[string]output),
+		now:      time.Now,
+	}
+}
+
+// ExecResolver resolves the name of a command to its output, with leading and trailing whitespace removed. Only
+// commands defined up front can be run, which keeps arbitrary commands out of transaction files. It is safe for
+// concurrent use, although commands are run one at a time.
+type ExecResolver struct {
+	dir      string
+	commands map[string]Command
+	mu       sync.Mutex
+	cache    map[string]output
+	now      func() time.Time
+}
+
+type output struct {
+	value   string
+	expires time.Time
+}
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (e *ExecResolver) Resolve(k string) (string, error) {
+	cmd, ok := e.commands[k]
+	if !ok {
+		return "", fmt.Errorf("failed to resolve key '%s': no such command: %w", k, ErrKeyNotFound)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if out, ok := e.cache[k]; ok && e.now().Before(out.expires) {
+		return out.value, nil
+	}
+	value, err := e.run(k, cmd)
+	if err != nil {
+		return "", err
+	}
+	if cmd.TTL > 0 {
+		e.cache[k] = output{value: value, expires: e.now().Add(cmd.TTL)}
+	}
+	return value, nil
+}
+
+func (e *ExecResolver) run(name string, cmd Command) (string, error) {
+	timeout := cmd.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", cmd.Run)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", cmd.Run)
+	}
+	c.Dir = e.dir
+	// Children of the shell may outlive it while holding on to its output, which would otherwise keep Wait blocked
+	// after the shell has been killed.
+	c.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, &stderr
+	err := c.Run()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("command %s timed out after %s", name, timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command %s failed: %w: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("command %s failed: %w", name, err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,81 @@
+package pia
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCommands(t *testing.T) {
+	commands, err := ParseCommands(strings.NewReader("token:\n  run: echo token\n  ttl: 5m\n  timeout: 10s\nuser:\n  run: whoami\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Command{
+		"token": {Run: "echo token", TTL: 5 * time.Minute, Timeout: 10 * time.Second},
+		"user":  {Run: "whoami"},
+	}, commands)
+
+	_, err = ParseCommands(strings.NewReader("token:\n  ttl: 5m\n"))
+	assert.ErrorContains(t, err, "command token has nothing to run")
+}
+
+func TestExecResolver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are written for a POSIX shell")
+	}
+	wd := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(wd, "token.txt"), []byte("  s3cr3t \n"), 0666))
+	e := NewExecResolver(wd, map[string]Command{
+		"token":   {Run: "cat token.txt"},
+		"counter": {Run: "echo x >> count.txt && wc -l < count.txt", TTL: time.Minute},
+		"failing": {Run: "echo oops >&2; exit 3"},
+		"slow":    {Run: "sleep 10", Timeout: 50 * time.Millisecond},
+	})
+	now := time.Now()
+	e.now = func() time.Time { return now }
+
+	token, err := e.Resolve("token")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", token)
+
+	count, err := e.Resolve("counter")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", count)
+	count, err = e.Resolve("counter")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", count, "output should be reused within the TTL")
+	now = now.Add(time.Minute)
+	count, err = e.Resolve("counter")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", count, "output should not be reused once the TTL has passed")
+
+	_, err = e.Resolve("failing")
+	assert.ErrorContains(t, err, "command failing failed")
+	assert.ErrorContains(t, err, "oops")
+
+	start := time.Now()
+	_, err = e.Resolve("slow")
+	assert.ErrorContains(t, err, "command slow timed out after 50ms")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	_, err = e.Resolve("undefined")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+}
+
+func TestLoadExecResolver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are written for a POSIX shell")
+	}
+	wd := t.TempDir()
+	path := filepath.Join(wd, "commands.yml")
+	assert.Nil(t, os.WriteFile(path, []byte("dir:\n  run: basename \"$PWD\"\n"), 0666))
+	e, err := LoadExecResolver(path)
+	assert.Nil(t, err)
+	dir, err := e.Resolve("dir")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Base(wd), dir, "commands should run in the directory of the file")
+}
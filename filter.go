@@ -0,0 +1,48 @@
+package pia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Filters are the functions that may be applied to resolved values by naming them after the key, such as
+// "${props:name|urlencode}". Filters are applied in the order they are named.
+var Filters = map[string]func(v string) (string, error){
+	"urlencode": func(v string) (string, error) {
+		return url.QueryEscape(v), nil
+	},
+	"base64": func(v string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(v)), nil
+	},
+	"jsonescape": jsonEscape,
+	"upper": func(v string) (string, error) {
+		return strings.ToUpper(v), nil
+	},
+	"lower": func(v string) (string, error) {
+		return strings.ToLower(v), nil
+	},
+	"trim": func(v string) (string, error) {
+		return strings.TrimSpace(v), nil
+	},
+	"sha256": func(v string) (string, error) {
+		sum := sha256.Sum256([]byte(v))
+		return hex.EncodeToString(sum[:]), nil
+	},
+}
+
+// jsonEscape escapes v for placement within a JSON string, without adding the surrounding quotes.
+func jsonEscape(v string) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1], nil
+}
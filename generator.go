@@ -0,0 +1,176 @@
+package pia
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pin matches the suffix of a generator key which pins the generated value under a name.
+var pin = regexp.MustCompile(`#([A-Za-z0-9_-]+)$`)
+
+// NewGeneratorResolver returns a [pia.GeneratorResolver] without any pinned values.
+func NewGeneratorResolver() *GeneratorResolver {
+	return &GeneratorResolver{
+		pinned: make(map[string]string),
+		now:    time.Now,
+	}
+}
+
+// GeneratorResolver resolves keys to values generated on every resolution. The key names the generator, optionally
+// followed by arguments separated by colons:
+//
+//	uuid                  a random version 4 UUID
+//	ulid                  a ULID for the current time
+//	now[:format]          the current UTC time, formatted as RFC 3339 unless another format is given, which is either
+//	                      one of rfc3339, rfc3339nano, rfc1123, date and time or a Go time layout
+//	unix                  the current Unix time in seconds
+//	unix_ms               the current Unix time in milliseconds
+//	random_int:min:max    a random integer between min and max, both inclusive
+//	random_string:n       a random alphanumeric string of length n
+//	base64:text           the standard base64 encoding of text
+//
+// A key ending in #name pins the generated value under name, such that every key pinned under the same name resolves
+// to the value generated first until the resolver is reset. This allows for the same UUID to appear both in the body
+// and in a header of a transaction. It is safe for concurrent use.
+type GeneratorResolver struct {
+	mu     sync.Mutex
+	pinned map[string]string
+	now    func() time.Time
+}
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (g *GeneratorResolver) Resolve(k string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	name := ""
+	if m := pin.FindStringSubmatchIndex(k); m != nil {
+		name = k[m[2]:m[3]]
+		k = k[:m[0]]
+		if v, ok := g.pinned[name]; ok {
+			return v, nil
+		}
+	}
+	v, err := g.generate(k)
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		g.pinned[name] = v
+	}
+	return v, nil
+}
+
+// Reset implements the [pia.Resetter] interface by forgetting all pinned values.
+func (g *GeneratorResolver) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.pinned)
+}
+
+func (g *GeneratorResolver) generate(k string) (string, error) {
+	generator, arg, _ := strings.Cut(k, ":")
+	switch generator {
+	case "uuid":
+		return uuid()
+	case "ulid":
+		return ulid(g.now())
+	case "now":
+		return now(g.now().UTC(), arg), nil
+	case "unix":
+		return strconv.FormatInt(g.now().Unix(), 10), nil
+	case "unix_ms":
+		return strconv.FormatInt(g.now().UnixMilli(), 10), nil
+	case "random_int":
+		lo, hi, ok := strings.Cut(arg, ":")
+		low, err := strconv.ParseInt(lo, 10, 64)
+		if err != nil || !ok {
+			return "", fmt.Errorf("random_int expects the arguments min:max but got '%s'", arg)
+		}
+		high, err := strconv.ParseInt(hi, 10, 64)
+		if err != nil || high < low {
+			return "", fmt.Errorf("random_int expects the arguments min:max but got '%s'", arg)
+		}
+		// The size of the range is computed as an unsigned integer, which only overflows, to zero, when the range
+		// spans every int64.
+		n := mrand.Uint64()
+		if size := uint64(high-low) + 1; size != 0 {
+			n = mrand.Uint64N(size)
+		}
+		return strconv.FormatInt(int64(uint64(low)+n), 10), nil
+	case "random_string":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("random_string expects a length but got '%s'", arg)
+		}
+		const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphabet[mrand.IntN(len(alphabet))]
+		}
+		return string(b), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(arg)), nil
+	default:
+		return "", fmt.Errorf("failed to resolve key '%s': no such generator: %w", k, ErrKeyNotFound)
+	}
+}
+
+func uuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+// ulid returns a ULID, that is a 48-bit millisecond timestamp followed by 80 random bits, in Crockford's base32.
+func ulid(t time.Time) (string, error) {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// 128 bits are encoded as 26 characters of 5 bits each, with the first character holding only 3 bits.
+	hi, lo := uint64(0), uint64(0)
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(b[i])
+		lo = lo<<8 | uint64(b[8+i])
+	}
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = alphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+func now(t time.Time, format string) string {
+	layouts := map[string]string{
+		"":            time.RFC3339,
+		"rfc3339":     time.RFC3339,
+		"rfc3339nano": time.RFC3339Nano,
+		"rfc1123":     time.RFC1123,
+		"date":        time.DateOnly,
+		"time":        time.TimeOnly,
+	}
+	if layout, ok := layouts[format]; ok {
+		return t.Format(layout)
+	}
+	return t.Format(format)
+}
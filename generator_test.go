@@ -0,0 +1,94 @@
+package pia
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGeneratorResolver_Resolve(t *testing.T) {
+	g := NewGeneratorResolver()
+	g.now = func() time.Time {
+		return time.Date(2024, time.March, 1, 12, 30, 45, 500_000_000, time.FixedZone("CET", 3600))
+	}
+	tests := []struct {
+		key     string
+		matches string
+	}{
+		{key: "uuid", matches: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{key: "ulid", matches: `^01HQWWFV3W[0-9A-HJKMNP-TV-Z]{16}$`},
+		{key: "now", matches: `^2024-03-01T11:30:45Z$`},
+		{key: "now:rfc3339nano", matches: `^2024-03-01T11:30:45\.5Z$`},
+		{key: "now:date", matches: `^2024-03-01$`},
+		{key: "now:15:04", matches: `^11:30$`},
+		{key: "unix", matches: `^1709292645$`},
+		{key: "unix_ms", matches: `^1709292645500$`},
+		{key: "random_int:1:3", matches: `^[123]$`},
+		{key: "random_int:-5:-5", matches: `^-5$`},
+		{key: "random_int:-9223372036854775808:9223372036854775807", matches: `^-?[0-9]+$`},
+		{key: "random_int:-9223372036854775808:-9223372036854775808", matches: `^-9223372036854775808$`},
+		{key: "random_int:9223372036854775806:9223372036854775807", matches: `^922337203685477580[67]$`},
+		{key: "random_int:-1:9223372036854775807", matches: `^(-1|[0-9]+)$`},
+		{key: "random_string:16", matches: `^[A-Za-z0-9]{16}$`},
+		{key: "base64:user:pass", matches: `^dXNlcjpwYXNz$`},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			v, err := g.Resolve(test.key)
+			assert.Nil(t, err)
+			assert.Regexp(t, regexp.MustCompile(test.matches), v)
+		})
+	}
+}
+
+func TestGeneratorResolver_Resolve_Error(t *testing.T) {
+	g := NewGeneratorResolver()
+	_, err := g.Resolve("unknown")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+	for _, key := range []string{"random_int", "random_int:1", "random_int:5:1", "random_int:a:b", "random_string:x"} {
+		_, err := g.Resolve(key)
+		assert.NotNil(t, err, key)
+		assert.False(t, errors.Is(err, ErrKeyNotFound), key)
+	}
+}
+
+func TestGeneratorResolver_Pin(t *testing.T) {
+	g := NewGeneratorResolver()
+	first, err := g.Resolve("uuid")
+	assert.Nil(t, err)
+	second, err := g.Resolve("uuid")
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, second, "values should be generated per resolution")
+
+	pinned, err := g.Resolve("uuid#order")
+	assert.Nil(t, err)
+	again, err := g.Resolve("uuid#order")
+	assert.Nil(t, err)
+	assert.Equal(t, pinned, again)
+	other, err := g.Resolve("uuid#customer")
+	assert.Nil(t, err)
+	assert.NotEqual(t, pinned, other)
+
+	// Resetting is expected to propagate through decorating and delegating resolvers.
+	Reset(FallbackResolverDecorator{Delegate: DelegatingKeyResolver{Delegates: map[string]KeyResolver{"gen": g}}})
+	reset, err := g.Resolve("uuid#order")
+	assert.Nil(t, err)
+	assert.NotEqual(t, pinned, reset)
+}
+
+func TestGeneratorResolver_RandomInt(t *testing.T) {
+	g := NewGeneratorResolver()
+	seen := make(map[int]bool)
+	for range 200 {
+		v, err := g.Resolve("random_int:1:4")
+		assert.Nil(t, err)
+		n, err := strconv.Atoi(v)
+		assert.Nil(t, err)
+		assert.True(t, n >= 1 && n <= 4)
+		seen[n] = true
+	}
+	assert.Len(t, seen, 4)
+}
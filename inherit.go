@@ -0,0 +1,209 @@
+package pia
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultsFile is the name of the file which, when present in a directory, holds configuration shared by the
+// transactions of the directory and of its subdirectories.
+const DefaultsFile = "_defaults.yml"
+
+var ErrCyclicExtends = errors.New("transaction extends itself")
+
+// inherit merges cfg, the configuration of a transaction in the directory wd, on top of the configurations it inherits
+// from. Those are, from the most general to the most specific, the defaults files of the directories from root down to
+// wd, each along with the files it extends, followed by the files extended by cfg. Inherited files are read using
+// decode. Any relative file paths of inherited configurations are rewritten to be relative to wd.
+func inherit(cfg *transaction, wd, root string, decode func(path string) (transaction, error)) error {
+	var layers []transaction
+	done, active := make(map[string]bool), make(map[string]bool)
+	var extend func(cfg transaction, dir string) error
+	extend = func(cfg transaction, dir string) error {
+		if cfg.Extends != "" {
+			path := cfg.Extends
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if err := load(path, done, active, decode, extend); err != nil {
+				return err
+			}
+		}
+		cfg.relocate(dir, wd)
+		layers = append(layers, cfg)
+		return nil
+	}
+
+	dirs := directories(wd, root)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		path := filepath.Join(dirs[i], DefaultsFile)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := load(path, done, active, decode, extend); err != nil {
+			return err
+		}
+	}
+	if err := extend(*cfg, wd); err != nil {
+		return err
+	}
+	merged := layers[0]
+	for _, layer := range layers[1:] {
+		merged = layer.inherit(merged)
+	}
+	*cfg = merged
+	return nil
+}
+
+// load decodes the inherited file at path and passes it on to extend, unless the file has already been inherited.
+// Files that end up extending themselves are reported as [pia.ErrCyclicExtends].
+func load(
+	path string,
+	done, active map[string]bool,
+	decode func(path string) (transaction, error),
+	extend func(cfg transaction, dir string) error,
+) error {
+	id, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if active[id] {
+		return fmt.Errorf("%w: %s", ErrCyclicExtends, path)
+	}
+	if done[id] {
+		return nil
+	}
+	cfg, err := decode(path)
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	active[id] = true
+	defer delete(active, id)
+	if err := extend(cfg, filepath.Dir(path)); err != nil {
+		return err
+	}
+	done[id] = true
+	return nil
+}
+
+// directories returns wd followed by its parent directories up to and including root. Only wd is returned if root is
+// empty or if wd is not within root.
+func directories(wd, root string) []string {
+	dirs := []string{wd}
+	if root == "" {
+		return dirs
+	}
+	top, err := filepath.Abs(root)
+	if err != nil {
+		return dirs
+	}
+	dir, err := filepath.Abs(wd)
+	if err != nil {
+		return dirs
+	}
+	up, err := filepath.Rel(top, dir)
+	if err != nil || up == ".." || strings.HasPrefix(up, ".."+string(filepath.Separator)) {
+		return dirs
+	}
+	for rel := wd; dir != top; {
+		rel, dir = filepath.Join(rel, ".."), filepath.Dir(dir)
+		dirs = append(dirs, rel)
+	}
+	return dirs
+}
+
+// inherit returns the configuration resulting from cfg inheriting from parent. Headers and query parameters are merged
+// by key, with headers matched regardless of case, while the body and client replace those of parent when set. Hooks
+// are chained such that the before hook of parent runs before that of cfg, and its after hook runs after that of cfg.
+// A target starting with a slash is appended to the target of parent, which lets a parent hold the base URL.
+func (cfg transaction) inherit(parent transaction) transaction {
+	if cfg.Method == "" {
+		cfg.Method = parent.Method
+	}
+	switch {
+	case cfg.URL.Target == "":
+		cfg.URL.Target = parent.URL.Target
+	case strings.HasPrefix(cfg.URL.Target, "/") && parent.URL.Target != "":
+		cfg.URL.Target = strings.TrimSuffix(parent.URL.Target, "/") + cfg.URL.Target
+	}
+	query := maps.Clone(parent.URL.Query)
+	if query == nil {
+		query = make(map[string]string)
+	}
+	maps.Copy(query, cfg.URL.Query)
+	if len(query) > 0 {
+		cfg.URL.Query = query
+	}
+	headers := maps.Clone(parent.Headers)
+	for k, v := range cfg.Headers {
+		for h := range headers {
+			if strings.EqualFold(h, k) {
+				delete(headers, h)
+			}
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[k] = v
+	}
+	cfg.Headers = headers
+	if cfg.Body.IsZero() {
+		cfg.Body = parent.Body
+	}
+	if cfg.Client == nil {
+		cfg.Client = parent.Client
+	}
+	cfg.before = append(append([]input{}, parent.before...), cfg.before...)
+	cfg.after = append(append([]input{}, cfg.after...), parent.after...)
+	return cfg
+}
+
+// relocate rewrites the relative file paths of the configuration, which are relative to the directory from, to be
+// relative to the directory to. It also collects the hooks of the configuration, which must be done before it inherits.
+func (cfg *transaction) relocate(from, to string) {
+	cfg.before, cfg.after = nil, nil
+	if cfg.Hooks.Before != (input{}) {
+		cfg.before = []input{cfg.Hooks.Before}
+	}
+	if cfg.Hooks.After != (input{}) {
+		cfg.after = []input{cfg.Hooks.After}
+	}
+	if from == to {
+		return
+	}
+	path := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		p = filepath.Join(from, p)
+		if rel, err := filepath.Rel(to, p); err == nil {
+			return rel
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			return abs
+		}
+		return p
+	}
+	cfg.Body.File = path(cfg.Body.File)
+	for i := range cfg.Body.Multipart {
+		cfg.Body.Multipart[i].File = path(cfg.Body.Multipart[i].File)
+	}
+	for i := range cfg.before {
+		cfg.before[i].File = path(cfg.before[i].File)
+	}
+	for i := range cfg.after {
+		cfg.after[i].File = path(cfg.after[i].File)
+	}
+	if c := cfg.Client; c != nil {
+		c.CAFile, c.CertFile, c.KeyFile = path(c.CAFile), path(c.CertFile), path(c.KeyFile)
+	}
+}
@@ -0,0 +1,149 @@
+package pia_test
+
+import (
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tree writes the files to the directory root, creating any directories along the way.
+func tree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0777))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0666))
+	}
+}
+
+func TestParseTransaction_Inheritance(t *testing.T) {
+	root := t.TempDir()
+	tree(t, root, map[string]string{
+		pia.DefaultsFile: strings.Join([]string{
+			"url:",
+			"  target: https://example.com/api/",
+			"  query:",
+			"    version: '1'",
+			"headers:",
+			"  Authorization: Bearer ${token}",
+			"  Content-Type: text/plain",
+			"hooks:",
+			"  before:",
+			"    inline: print(\"defaults before\");",
+			"  after:",
+			"    file: after.sqk",
+		}, "\n"),
+		"after.sqk": "print(\"defaults after\");",
+		"users/base.yml": strings.Join([]string{
+			"method: POST",
+			"headers:",
+			"  content-type: application/json",
+			"body:",
+			"  file: user.json",
+			"hooks:",
+			"  before:",
+			"    inline: print(\"base before\");",
+		}, "\n"),
+		"users/user.json": `{"name": "${name}"}`,
+	})
+	wd := filepath.Join(root, "users", "create")
+	assert.Nil(t, os.MkdirAll(wd, 0777))
+	cfg := strings.Join([]string{
+		"extends: ../base.yml",
+		"url:",
+		"  target: /users",
+		"  query:",
+		"    dry_run: 'true'",
+		"hooks:",
+		"  before:",
+		"    inline: print(\"tx before\");",
+		"  after:",
+		"    inline: print(\"tx after\");",
+	}, "\n")
+	resolver := pia.MapResolver{"token": "secret", "name": "pia"}
+	read := func(r io.Reader) string {
+		t.Helper()
+		data, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		return string(data)
+	}
+
+	t.Run("merged", func(t *testing.T) {
+		tx, err := pia.ParseTransaction(
+			wd,
+			strings.NewReader(cfg),
+			pia.WithResolver(resolver),
+			pia.WithRoot(root),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "POST", tx.Method)
+		assert.Equal(t, "https://example.com/api/users", tx.URL.Target)
+		assert.Equal(t, map[string]string{"version": "1", "dry_run": "true"}, tx.URL.Query)
+		assert.Equal(t, map[string]string{
+			"Authorization": "Bearer secret",
+			"content-type":  "application/json",
+		}, tx.Headers)
+		assert.Equal(t, `{"name": "pia"}`, read(tx.Body))
+		assert.Equal(
+			t,
+			"print(\"defaults before\");\nprint(\"base before\");\nprint(\"tx before\");",
+			read(tx.Hooks.Before),
+		)
+		assert.Equal(t, "print(\"tx after\");\nprint(\"defaults after\");", read(tx.Hooks.After))
+	})
+
+	t.Run("without root", func(t *testing.T) {
+		tx, err := pia.ParseTransaction(wd, strings.NewReader(cfg), pia.WithResolver(resolver))
+		assert.Nil(t, err)
+		assert.Equal(t, "/users", tx.URL.Target)
+		assert.Equal(t, map[string]string{"content-type": "application/json"}, tx.Headers)
+		assert.Equal(t, "print(\"base before\");\nprint(\"tx before\");", read(tx.Hooks.Before))
+	})
+
+	t.Run("body overridden", func(t *testing.T) {
+		tx, err := pia.ParseTransaction(
+			wd,
+			strings.NewReader(cfg+"\nbody:\n  inline: overridden"),
+			pia.WithRoot(root),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "overridden", read(tx.Body))
+		assert.Equal(t, "Bearer ${token}", tx.Headers["Authorization"])
+	})
+
+	t.Run("analysis", func(t *testing.T) {
+		path := filepath.Join(wd, "tx.yml")
+		assert.Nil(t, os.WriteFile(path, []byte(cfg), 0666))
+		analysis, err := pia.Analyze(path, map[string]pia.KeyResolver{}, pia.WithRoot(root))
+		assert.Nil(t, err)
+		assert.Empty(t, analysis.Problems)
+		var files []string
+		for _, ref := range analysis.References {
+			files = append(files, filepath.Base(ref.File)+":"+ref.Key)
+		}
+		assert.Equal(t, []string{pia.DefaultsFile + ":token", "user.json:name"}, files)
+	})
+}
+
+func TestParseTransaction_InheritanceErrors(t *testing.T) {
+	wd := t.TempDir()
+	tree(t, wd, map[string]string{
+		"a.yml": "extends: b.yml",
+		"b.yml": "extends: a.yml",
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := pia.ParseTransaction(wd, strings.NewReader("extends: a.yml"))
+		assert.ErrorIs(t, err, pia.ErrCyclicExtends)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := pia.ParseTransaction(wd, strings.NewReader("extends: missing.yml"))
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
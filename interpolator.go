@@ -4,152 +4,256 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
-	"regexp"
+	"strings"
 )
 
 var (
-	ErrKeyNotFound                   = errors.New("key not found")
+	ErrKeyNotFound = errors.New("key not found")
+	// Deprecated: an [pia.Interpolator] accepts destinations of any size and no longer returns this error.
 	ErrInsufficientDestinationLength = errors.New("destination size for substituting reader must be greater than 2")
+	ErrUnterminatedPlaceholder       = errors.New("unterminated substitution point")
+	ErrKeyTooLong                    = errors.New("substitution point exceeds the maximum key length")
 )
 
+// DefaultMaxKeyLength is the maximum length of the key of a substitution point, including any modifiers, unless
+// another maximum is given using [pia.WithMaxKeyLength].
+const DefaultMaxKeyLength = 4096
+
+// InterpolationError reports a malformed substitution point starting at Offset, which is the number of bytes from the
+// start of the interpolated stream.
+type InterpolationError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Err, e.Offset)
+}
+
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// InterpolatorOption configures an [pia.Interpolator].
+type InterpolatorOption func(ip *Interpolator)
+
+// WithMaxKeyLength limits the length of the keys of substitution points to n bytes, which bounds how far an
+// [pia.Interpolator] reads ahead looking for the end of a substitution point.
+func WithMaxKeyLength(n int) InterpolatorOption {
+	return func(ip *Interpolator) {
+		ip.max = n
+	}
+}
+
 // WrapReader returns a [pia.Interpolator] that uses the supplied [pia.KeyResolver] as source for substitutions
 // and the supplied [io.Reader] as its target.
-func WrapReader(resolver KeyResolver, r io.Reader) *Interpolator {
-	return &Interpolator{
-		prefix:   regexp.MustCompile(`\${`),
-		matcher:  regexp.MustCompile(`\${(.+?)}`),
+func WrapReader(resolver KeyResolver, r io.Reader, opts ...InterpolatorOption) *Interpolator {
+	ip := &Interpolator{
 		resolver: resolver,
 		wrapped:  bufio.NewReader(r),
-		carry:    bytes.NewBuffer(make([]byte, 0)),
+		max:      DefaultMaxKeyLength,
 	}
+	for _, opt := range opts {
+		opt(ip)
+	}
+	return ip
+}
+
+// escapePlaceholders escapes every "${" of s as "$${", such that interpolating the result yields s. It is used for text
+// taken from other tools, in which "${" has no special meaning.
+func escapePlaceholders(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
 }
 
+// state is the state of the tokenizer of an [pia.Interpolator].
+type state int
+
+const (
+	// inText is the state outside substitution points.
+	inText state = iota
+	// afterDollar is the state after a dollar sign, which might be the start of a substitution point.
+	afterDollar
+	// afterDollars is the state after two dollar signs, which might be the start of an escaped substitution point.
+	afterDollars
+	// inKey is the state within a substitution point.
+	inKey
+)
+
 // Interpolator decorates the wrapped reader by replacing any occurrences of substitution points defined using
 // "${key}" syntax with the corresponding value according to the supplied [pia.KeyResolver]. Since a [pia.KeyResolver]
 // is powering the substitution, all values must be supplied as strings and will be placed unquoted into the stream.
+// Substitution points may be nested within each other, which is how defaults referring to other keys are expressed,
+// and "$${" is replaced by a literal "${" without anything being substituted.
+//
+// The wrapped reader is tokenized a byte at a time, which means that no more than the maximum key length is ever read
+// ahead, and that destinations of any size can be read into.
 type Interpolator struct {
-	prefix  *regexp.Regexp
-	matcher *regexp.Regexp
-
 	resolver KeyResolver
 	wrapped  *bufio.Reader
-	carry    io.ReadWriter
+	max      int
+
+	state state
+	// offset is the number of bytes consumed from the wrapped reader, and start is the offset of the substitution point
+	// being tokenized.
+	offset int64
+	start  int64
+	// depth is the number of substitution points being nested within, prev is the previously consumed byte and buf
+	// holds the key of the substitution point.
+	depth int
+	prev  byte
+	buf   bytes.Buffer
+	// out holds interpolated data that is yet to be read.
+	out bytes.Buffer
+	eof bool
+	err error
 }
 
 // Read implements the [io.Reader] interface for seamless interoperability with the Go standard library.
-func (ip Interpolator) Read(p []byte) (int, error) {
-	if len(p) < 2 {
-		// Destination must be able to contain at least "${" for Interpolator to be able to find substitution
-		// points. As such, the buffer must be at least two bytes long.
-		return 0, ErrInsufficientDestinationLength
+func (ip *Interpolator) Read(p []byte) (int, error) {
+	for ip.out.Len() < len(p) && !ip.eof && ip.err == nil {
+		ip.err = ip.step()
 	}
-	str, err := ip.read(len(p))
-	if err != nil {
-		return 0, err
+	if ip.err != nil {
+		return 0, ip.err
 	}
-	for _, match := range ip.match(str) {
-		str, err = ip.substitute(str, match)
-		if err != nil {
-			return 0, err
-		}
+	if ip.out.Len() == 0 && ip.eof {
+		return 0, io.EOF
 	}
-	copy(p, str)
-	if len(str) > len(p) {
-		_, err := ip.carry.Write([]byte(str)[len(p):])
-		if err != nil {
-			return 0, err
-		}
-		return len(p), nil
-	}
-	return len(str), nil
+	return ip.out.Read(p)
 }
 
-// read returns the next processable chunk of data using ln as the pivoting length. It is possible that read returns
-// both shorter and longer strings based on the data residing within the raw read data.
-func (ip Interpolator) read(ln int) (string, error) {
-	str, err := ip.raw(ln)
+// step consumes a single byte from the wrapped reader following a dollar sign, or otherwise a run of bytes.
+func (ip *Interpolator) step() error {
+	switch ip.state {
+	case inText:
+		return ip.text()
+	case inKey:
+		return ip.key()
+	}
+	c, err := ip.wrapped.ReadByte()
+	if errors.Is(err, io.EOF) {
+		ip.eof = true
+		return ip.flush()
+	}
 	if err != nil {
-		return "", err
-	}
-	if str[len(str)-1] == '$' {
-		// There is a possibility that the next rune in from the reader could be an open curly brace, which together
-		// with the dollar sign becomes the prefix of data that should be substituted. Therefore, read the next byte and
-		// include it in the string being processed.
-		extra, err := ip.wrapped.ReadByte()
-		if err != nil && !errors.Is(err, io.EOF) {
-			return "", err
-		} else if err == nil {
-			str += string(extra)
-		}
+		return err
 	}
-	if ip.partials(str) {
-		// We need to go further into the wrapped reader to make sure we can substitute the next one as well
-		extra, err := ip.wrapped.ReadString('}')
-		if errors.Is(err, io.EOF) {
-			err = nil
+	ip.offset++
+	switch ip.state {
+	case afterDollar:
+		switch c {
+		case '{':
+			ip.state, ip.depth, ip.prev = inKey, 1, c
+			ip.buf.Reset()
+		case '$':
+			ip.state = afterDollars
+		default:
+			ip.state = inText
+			ip.out.WriteByte('$')
+			ip.out.WriteByte(c)
 		}
-		if err != nil {
-			return "", err
+	case afterDollars:
+		switch c {
+		case '{':
+			ip.state = inText
+			ip.out.WriteString("${")
+		case '$':
+			// Only the last two of three or more dollar signs might make up an escaped substitution point.
+			ip.out.WriteByte('$')
+		default:
+			ip.state = inText
+			ip.out.WriteString("$$")
+			ip.out.WriteByte(c)
 		}
-		str += extra
 	}
-	return str, nil
+	return nil
 }
 
-// raw returns ln or fewer bytes of data from the carry and wrapped readers without performing any modifications or
-// additions to the data.
-func (ip Interpolator) raw(ln int) (string, error) {
-	p := make([]byte, ln)
-	cn, err := ip.carry.Read(p)
-	if errors.Is(err, io.EOF) {
-		// The carry is expected to not contain data with each read and thus receiving an EOF error is considered
-		// normal.
-		cn = 0
-	} else if err != nil {
-		return "", err
+// text consumes everything up to and including the next dollar sign from the wrapped reader, or as much as is buffered
+// by it, which is then passed through as is.
+func (ip *Interpolator) text() error {
+	chunk, err := ip.wrapped.ReadSlice('$')
+	ip.offset += int64(len(chunk))
+	if len(chunk) > 0 && chunk[len(chunk)-1] == '$' {
+		ip.out.Write(chunk[:len(chunk)-1])
+		ip.state, ip.start = afterDollar, ip.offset-1
+		return nil
 	}
-	if cn == len(p) {
-		return string(p), nil
+	ip.out.Write(chunk)
+	switch {
+	case errors.Is(err, bufio.ErrBufferFull):
+		return nil
+	case errors.Is(err, io.EOF):
+		ip.eof = true
+		return ip.flush()
+	default:
+		return err
 	}
-
-	wn, err := ip.wrapped.Read(p[cn:])
-	if errors.Is(err, io.EOF) && cn > 0 {
-		// If the wrapped reader has reached EOF, but there is data still being read from the carry then continue
-		// reading only from the carry.
-		return string(p[:cn]), nil
-	} else if err != nil {
-		return "", err
-	}
-
-	return string(p[:cn+wn]), nil
 }
 
-func (ip Interpolator) substitute(str string, match []string) (string, error) {
-	val, err := ip.resolver.Resolve(match[1])
-	if err != nil {
-		return "", err
+// key consumes everything up to and including the next closing brace from the wrapped reader, or as much as is
+// buffered by it, which becomes part of the key of the current substitution point.
+func (ip *Interpolator) key() error {
+	chunk, err := ip.wrapped.ReadSlice('}')
+	ip.offset += int64(len(chunk))
+	for i, c := range chunk {
+		switch {
+		case c == '{' && ip.prev == '$':
+			ip.depth++
+		case c == '}':
+			ip.depth--
+		}
+		ip.prev = c
+		if ip.depth == 0 {
+			if ip.buf.Len()+i > ip.max {
+				return &InterpolationError{Offset: ip.start, Err: ErrKeyTooLong}
+			}
+			ip.buf.Write(chunk[:i])
+			ip.state = inText
+			return ip.substitute()
+		}
+	}
+	if ip.buf.Len()+len(chunk) > ip.max {
+		return &InterpolationError{Offset: ip.start, Err: ErrKeyTooLong}
+	}
+	ip.buf.Write(chunk)
+	switch {
+	case err == nil, errors.Is(err, bufio.ErrBufferFull):
+		return nil
+	case errors.Is(err, io.EOF):
+		ip.eof = true
+		return ip.flush()
+	default:
+		return err
 	}
-	// Compiles a new [regexp.Regexp] value with each substitution which is not optimal for performance. Remains to be
-	// measured and benchmarked.
-	return regexp.MustCompile(regexp.QuoteMeta(match[0])).ReplaceAllLiteralString(str, val), nil
-}
-
-func (ip Interpolator) match(str string) [][]string {
-	return ip.matcher.FindAllStringSubmatch(str, -1)
 }
 
-// partials reports whether the supplied string is potentially containing partial substitution points with respect to
-// the rest of the stream. However, this cannot be guaranteed without potentially reading the entire wrapped stream and
-// checking whether there exists a terminating character "}".
-func (ip Interpolator) partials(str string) bool {
-	return ip.prefixes(str) > ip.points(str)
+// flush completes the tokenization once the wrapped reader has been exhausted.
+func (ip *Interpolator) flush() error {
+	switch ip.state {
+	case afterDollar:
+		ip.out.WriteByte('$')
+	case afterDollars:
+		ip.out.WriteString("$$")
+	case inKey:
+		return &InterpolationError{Offset: ip.start, Err: ErrUnterminatedPlaceholder}
+	}
+	return nil
 }
 
-func (ip Interpolator) points(str string) int {
-	return len(ip.matcher.FindAllStringIndex(str, -1))
+func (ip *Interpolator) substitute() error {
+	val, err := ip.resolver.Resolve(ip.buf.String())
+	if err != nil {
+		return err
+	}
+	ip.out.WriteString(val)
+	return nil
 }
 
-func (ip Interpolator) prefixes(str string) int {
-	return len(ip.prefix.FindAllStringIndex(str, -1))
+// interpolate replaces every substitution point in str with its value according to resolver.
+func interpolate(resolver KeyResolver, str string) (string, error) {
+	out, err := io.ReadAll(WrapReader(resolver, strings.NewReader(str)))
+	return string(out), err
 }
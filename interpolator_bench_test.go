@@ -0,0 +1,196 @@
+package pia
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func wrapChunked(resolver KeyResolver, r io.Reader) *chunkedInterpolator {
+	return &chunkedInterpolator{
+		resolver: resolver,
+		wrapped:  bufio.NewReader(r),
+		carry:    bytes.NewBuffer(make([]byte, 0)),
+	}
+}
+
+// chunkedInterpolator is the implementation of [pia.Interpolator] which preceded the tokenizer, kept as a baseline for
+// the benchmarks. It interpolates whatever a read of the wrapped reader returns as a whole, reading further whenever a
+// substitution point is cut off.
+type chunkedInterpolator struct {
+	resolver KeyResolver
+	wrapped  *bufio.Reader
+	// carry holds interpolated data that did not fit into the destination of a previous read.
+	carry *bytes.Buffer
+}
+
+func (ip chunkedInterpolator) Read(p []byte) (int, error) {
+	if len(p) < 2 {
+		// Destination must be able to contain at least "${" for Interpolator to be able to find substitution
+		// points. As such, the buffer must be at least two bytes long.
+		return 0, ErrInsufficientDestinationLength
+	}
+	if ip.carry.Len() > 0 {
+		// Data in the carry has already been interpolated and must not be interpolated again.
+		return ip.carry.Read(p)
+	}
+	str, err := ip.read(len(p))
+	if err != nil {
+		return 0, err
+	}
+	str, err = interpolateChunk(ip.resolver, str)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, str)
+	ip.carry.WriteString(str[n:])
+	return n, nil
+}
+
+func (ip chunkedInterpolator) read(ln int) (string, error) {
+	p := make([]byte, ln)
+	n, err := ip.wrapped.Read(p)
+	if err != nil {
+		return "", err
+	}
+	str := string(p[:n])
+	for strings.HasSuffix(str, "$") {
+		// There is a possibility that the next rune in from the reader could be an open curly brace, which together
+		// with the dollar sign becomes the prefix of data that should be substituted. Therefore, read the next byte and
+		// include it in the string being processed. Another dollar sign might make it an escaped prefix instead.
+		extra, err := ip.wrapped.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		str += string(extra)
+	}
+	for pendingChunk(str) {
+		// We need to go further into the wrapped reader to make sure we can substitute the next one as well
+		extra, err := ip.wrapped.ReadString('}')
+		str += extra
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return str, nil
+}
+
+func interpolateChunk(resolver KeyResolver, str string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(str, '$')
+		if i == -1 {
+			b.WriteString(str)
+			return b.String(), nil
+		}
+		b.WriteString(str[:i])
+		str = str[i:]
+		switch {
+		case strings.HasPrefix(str, "$${"):
+			b.WriteString("${")
+			str = str[3:]
+		case strings.HasPrefix(str, "${"):
+			end := closingChunk(str)
+			if end == -1 {
+				b.WriteString(str)
+				return b.String(), nil
+			}
+			val, err := resolver.Resolve(str[2:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+			str = str[end+1:]
+		default:
+			b.WriteByte('$')
+			str = str[1:]
+		}
+	}
+}
+
+func closingChunk(str string) int {
+	depth := 0
+	for i := 0; i < len(str); i++ {
+		switch {
+		case str[i] == '$' && i+1 < len(str) && str[i+1] == '{':
+			depth++
+			i++
+		case str[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func pendingChunk(str string) bool {
+	for {
+		i := strings.Index(str, "${")
+		if i == -1 {
+			return false
+		}
+		if i > 0 && str[i-1] == '$' {
+			str = str[i+2:]
+			continue
+		}
+		end := closingChunk(str[i:])
+		if end == -1 {
+			return true
+		}
+		str = str[i+end+1:]
+	}
+}
+
+// benchmarkBody returns a JSON array of n objects, each holding a couple of substitution points.
+func benchmarkBody(n int) string {
+	const row = `  {"id": %d, "user": "${props:user}", "token": "${props:token|none}", "note": "$${literal}"},` + "\n"
+	var b strings.Builder
+	b.WriteString("[\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, row, i)
+	}
+	b.WriteString("]\n")
+	return b.String()
+}
+
+func BenchmarkInterpolator(b *testing.B) {
+	resolver := FallbackResolverDecorator{
+		Delegate: DelegatingKeyResolver{
+			Delegates: map[string]KeyResolver{
+				"props": MapResolver{"user": "pia", "token": "s3cr3t"},
+			},
+		},
+	}
+	implementations := []struct {
+		name string
+		wrap func(r io.Reader) io.Reader
+	}{
+		{name: "tokenizer", wrap: func(r io.Reader) io.Reader { return WrapReader(resolver, r) }},
+		{name: "chunked", wrap: func(r io.Reader) io.Reader { return wrapChunked(resolver, r) }},
+	}
+	for _, rows := range []int{1_000, 100_000} {
+		body := benchmarkBody(rows)
+		for _, impl := range implementations {
+			b.Run(fmt.Sprintf("%s/%dKiB", impl.name, len(body)/1024), func(b *testing.B) {
+				b.SetBytes(int64(len(body)))
+				for b.Loop() {
+					if _, err := io.Copy(io.Discard, impl.wrap(strings.NewReader(body))); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
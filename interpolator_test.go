@@ -2,6 +2,7 @@ package pia_test
 
 import (
 	"errors"
+	"fmt"
 	"github.com/ernilsson/pia"
 	"github.com/stretchr/testify/assert"
 	"io"
@@ -67,13 +68,13 @@ func TestSubstitutingReader_Read(t *testing.T) {
 				readBufferSize: 4,
 			},
 			{
-				name: "non-terminated substitution point",
+				name: "single byte buffer",
 				resolver: map[string]string{
 					"env.username": "pia",
 				},
-				input:          "${env.username",
-				expected:       "${env.username",
-				readBufferSize: 4,
+				input:          "user: ${env.username}$",
+				expected:       "user: pia$",
+				readBufferSize: 1,
 			},
 		}
 		for _, test := range tests {
@@ -115,10 +116,11 @@ func TestSubstitutingReader_Read(t *testing.T) {
 				readBufferSize: 512,
 			},
 			{
-				name:           "insufficient destination length",
-				input:          "${env.username}",
-				err:            pia.ErrInsufficientDestinationLength,
-				readBufferSize: 1,
+				name:           "non-terminated substitution point",
+				input:          "${env.username",
+				resolver:       map[string]string{"env.username": "pia"},
+				err:            pia.ErrUnterminatedPlaceholder,
+				readBufferSize: 4,
 			},
 		}
 		for _, test := range tests {
@@ -142,3 +144,99 @@ func TestSubstitutingReader_Read(t *testing.T) {
 		}
 	})
 }
+
+func TestInterpolator_Syntax(t *testing.T) {
+	resolver := pia.FallbackResolverDecorator{
+		Delegate: pia.DelegatingKeyResolver{
+			Delegates: map[string]pia.KeyResolver{
+				"props": pia.MapResolver{
+					"user":  "pia",
+					"query": "a&b",
+					"value": "${props:user}",
+				},
+				"env": pia.MapResolver{
+					"HOST": "example.com",
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "escaped prefix", input: "echo $${HOME} as ${props:user}", expected: "echo ${HOME} as pia"},
+		{name: "escaped prefix at end", input: "$${", expected: "${"},
+		{name: "dollar signs without braces", input: "P@$$W0RD $ $$", expected: "P@$$W0RD $ $$"},
+		{name: "dollar sign before escape", input: "$$${props:user}", expected: "$${props:user}"},
+		{name: "nested default", input: "http://${props:host|${env:HOST}}/", expected: "http://example.com/"},
+		{name: "deeply nested default", input: "${props:a|${props:b|${env:HOST}}}!", expected: "example.com!"},
+		{name: "filter", input: "/search?q=${props:query|urlencode}", expected: "/search?q=a%26b"},
+		{name: "values are not interpolated", input: "${props:value}", expected: "${props:user}"},
+		{name: "braces outside placeholders", input: `{"user": "${props:user}"}`, expected: `{"user": "pia"}`},
+	}
+	for _, test := range tests {
+		for _, size := range []int{2, 3, 5, 512} {
+			t.Run(fmt.Sprintf("%s with buffer size %d", test.name, size), func(t *testing.T) {
+				r := pia.WrapReader(resolver, strings.NewReader(test.input))
+				var actual string
+				for {
+					buf := make([]byte, size)
+					n, err := r.Read(buf)
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					assert.Nil(t, err)
+					actual += string(buf[:n])
+				}
+				assert.Equal(t, test.expected, actual)
+			})
+		}
+	}
+}
+
+func TestInterpolator_Read_Malformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		opts   []pia.InterpolatorOption
+		err    error
+		offset int64
+	}{
+		{name: "unterminated", input: "user: ${props:user\nnext: value\n", err: pia.ErrUnterminatedPlaceholder, offset: 6},
+		{name: "unterminated nested", input: "ok ${props:a|${props:b}", err: pia.ErrUnterminatedPlaceholder, offset: 3},
+		{name: "unterminated after dollar signs", input: "$$ ${x", err: pia.ErrUnterminatedPlaceholder, offset: 3},
+		{
+			name:   "key too long",
+			input:  "abc ${" + strings.Repeat("k", 64) + "}",
+			opts:   []pia.InterpolatorOption{pia.WithMaxKeyLength(32)},
+			err:    pia.ErrKeyTooLong,
+			offset: 4,
+		},
+		{
+			name:   "stray prefix",
+			input:  "${" + strings.Repeat("x", 2*pia.DefaultMaxKeyLength),
+			err:    pia.ErrKeyTooLong,
+			offset: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := pia.WrapReader(pia.MapResolver{}, strings.NewReader(test.input), test.opts...)
+			_, err := io.ReadAll(r)
+			assert.ErrorIs(t, err, test.err)
+			var ierr *pia.InterpolationError
+			if assert.ErrorAs(t, err, &ierr) {
+				assert.Equal(t, test.offset, ierr.Offset)
+			}
+		})
+	}
+}
+
+func TestInterpolator_Read_MaxKeyLength(t *testing.T) {
+	key := strings.Repeat("k", 32)
+	r := pia.WrapReader(pia.MapResolver{key: "v"}, strings.NewReader("${"+key+"}"), pia.WithMaxKeyLength(32))
+	out, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "v", string(out))
+}
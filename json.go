@@ -0,0 +1,75 @@
+package pia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+// jsonBody serializes the YAML tree rooted in node as a JSON document. Mappings keep the order of their keys and scalars
+// keep the type that YAML resolves them to, which means that quoting a scalar in YAML is the way to make it a string.
+func jsonBody(node *yaml.Node) (io.Reader, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := writeJSON(buf, node); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSON(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case yaml.ScalarNode:
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		buf.Write(data)
+		return nil
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node in JSON body", node.Line)
+	}
+}
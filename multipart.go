@@ -0,0 +1,104 @@
+package pia
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// part represents a single part of a multipart/form-data body in its textual YAML state. A part holds either an inline
+// value or the contents of a file, in which case the file name and content type of the part default to the base name
+// of the file and the content type implied by its extension.
+type part struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value,omitempty"`
+	File        string `yaml:"file,omitempty"`
+	Filename    string `yaml:"filename,omitempty"`
+	ContentType string `yaml:"content_type,omitempty"`
+}
+
+// withBoundary returns the declared content type with its boundary replaced by that of the content type the body was
+// encoded with, since the parts of the body can only be told apart using that boundary. Declared content types that
+// are not multipart are returned as is.
+func withBoundary(declared, encoded string) string {
+	mediatype, params, err := mime.ParseMediaType(declared)
+	if err != nil || !strings.HasPrefix(mediatype, "multipart/") {
+		return declared
+	}
+	_, encodedParams, err := mime.ParseMediaType(encoded)
+	if err != nil {
+		return declared
+	}
+	params["boundary"] = encodedParams["boundary"]
+	return mime.FormatMediaType(mediatype, params)
+}
+
+// multipartBody encodes the parts as a multipart/form-data body and returns it together with the content type, which
+// includes the boundary used to separate the parts.
+func multipartBody(wd string, parts []part) (io.Reader, string, error) {
+	buf := bytes.NewBuffer(nil)
+	w := multipart.NewWriter(buf)
+	for _, p := range parts {
+		if p.Name == "" {
+			return nil, "", errors.New("multipart body contains a part without a name")
+		}
+		if p.File == "" {
+			if err := w.WriteField(p.Name, p.Value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		if err := p.write(wd, w); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf, w.FormDataContentType(), nil
+}
+
+func (p part) write(wd string, w *multipart.Writer) error {
+	path := p.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(wd, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	filename := p.Filename
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	ct := p.ContentType
+	if ct == "" {
+		ct = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(
+		`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(p.Name),
+		quoteEscaper.Replace(filename),
+	))
+	h.Set("Content-Type", ct)
+	dst, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
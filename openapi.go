@@ -0,0 +1,458 @@
+package pia
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// openapiMethods are the keys of an OpenAPI path item which denote operations.
+var openapiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openapiPathParameter matches the templated segments of an OpenAPI path, such as {id} in /users/{id}.
+var openapiPathParameter = regexp.MustCompile(`{([^{}]+)}`)
+
+type openapiOperation struct {
+	OperationID string      `yaml:"operationId"`
+	Tags        []string    `yaml:"tags"`
+	Parameters  []yaml.Node `yaml:"parameters"`
+	RequestBody yaml.Node   `yaml:"requestBody"`
+	Responses   yaml.Node   `yaml:"responses"`
+}
+
+type openapiParameter struct {
+	Name     string    `yaml:"name"`
+	In       string    `yaml:"in"`
+	Required bool      `yaml:"required"`
+	Schema   yaml.Node `yaml:"schema"`
+	Example  yaml.Node `yaml:"example"`
+}
+
+// ImportOpenAPI generates a transaction file for every operation of an OpenAPI 3 document, given as YAML or JSON and
+// read from r. The transaction files are written to dir, grouped into directories by the first tag of each operation.
+//
+// Every transaction targets "${props:base_url}" followed by the path of its operation, where path parameters are
+// interpolated from the properties of the same name. Required query parameters and headers are included, and so are
+// optional query parameters with an example value. JSON request bodies are filled in with an example built from their
+// schema, and an after hook asserts the documented success status. The URL of the first server of the document is
+// written to a property file as base_url. The returned warnings describe the parts of the document that could not be
+// translated.
+func ImportOpenAPI(dir string, r io.Reader) ([]string, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("invalid openapi document: not a mapping")
+	}
+	im := openapiImporter{root: doc.Content[0]}
+	if version := lookup(im.root, "openapi"); version == nil || !strings.HasPrefix(version.Value, "3.") {
+		return nil, errors.New("invalid openapi document: only OpenAPI 3 is supported")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := im.servers(dir); err != nil {
+		return nil, err
+	}
+	paths := lookup(im.root, "paths")
+	if paths == nil {
+		return im.warnings, nil
+	}
+	taken := make(map[string]bool)
+	for i := 0; i+1 < len(paths.Content); i += 2 {
+		path := paths.Content[i].Value
+		item := im.resolve(path, paths.Content[i+1])
+		if item == nil {
+			continue
+		}
+		var common []yaml.Node
+		if params := lookup(item, "parameters"); params != nil {
+			if err := params.Decode(&common); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			method := item.Content[j].Value
+			if !slices.Contains(openapiMethods, method) {
+				continue
+			}
+			var op openapiOperation
+			if err := item.Content[j+1].Decode(&op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			file := im.file(dir, method, path, op, taken)
+			if err := im.operation(file, method, path, op, append(slices.Clone(common), op.Parameters...)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return im.warnings, nil
+}
+
+type openapiImporter struct {
+	root     *yaml.Node
+	warnings []string
+}
+
+func (im *openapiImporter) warn(path, format string, args ...any) {
+	im.warnings = append(im.warnings, path+": "+fmt.Sprintf(format, args...))
+}
+
+// servers writes the URL of the first server of the document as the base_url property.
+func (im *openapiImporter) servers(dir string) error {
+	servers := lookup(im.root, "servers")
+	if servers == nil || len(servers.Content) == 0 {
+		im.warn(dir, "the document lists no servers, base_url must be declared by hand")
+		return nil
+	}
+	var server struct {
+		URL       string `yaml:"url"`
+		Variables map[string]struct {
+			Default string `yaml:"default"`
+		} `yaml:"variables"`
+	}
+	if err := servers.Content[0].Decode(&server); err != nil {
+		return err
+	}
+	base := openapiPathParameter.ReplaceAllStringFunc(server.URL, func(m string) string {
+		return server.Variables[m[1:len(m)-1]].Default
+	})
+	return writeProperties(filepath.Join(dir, ImportedVariables), map[string]string{
+		"base_url": strings.TrimSuffix(base, "/"),
+	})
+}
+
+// file returns the path of the transaction file of an operation. Operations are named after their operation ID when
+// they have one, and after their method and path otherwise.
+func (im *openapiImporter) file(dir, method, path string, op openapiOperation, taken map[string]bool) string {
+	if len(op.Tags) > 0 {
+		dir = filepath.Join(dir, filename(op.Tags[0]))
+	}
+	name := method + " " + path
+	if op.OperationID != "" {
+		// Operation IDs are commonly camel cased, which would be unreadable once lower cased.
+		var b strings.Builder
+		for i, r := range op.OperationID {
+			if i > 0 && unicode.IsUpper(r) {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+		}
+		name = b.String()
+	}
+	base := filepath.Join(dir, filename(name))
+	file := base + ".yml"
+	for i := 2; taken[file]; i++ {
+		file = fmt.Sprintf("%s-%d.yml", base, i)
+	}
+	taken[file] = true
+	return file
+}
+
+func (im *openapiImporter) operation(file, method, path string, op openapiOperation, nodes []yaml.Node) error {
+	var cfg transaction
+	cfg.Method = strings.ToUpper(method)
+	cfg.URL.Target = "${props:base_url}" + openapiPathParameter.ReplaceAllString(path, "$${props:$1}")
+	params := make(map[string]openapiParameter)
+	var order []string
+	for i := range nodes {
+		node := im.resolve(file, &nodes[i])
+		if node == nil {
+			continue
+		}
+		var param openapiParameter
+		if err := node.Decode(&param); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		// Parameters of an operation override the parameters of its path with the same name and location.
+		key := param.In + ":" + param.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = param
+	}
+	for _, key := range order {
+		param := params[key]
+		value, ok := im.parameter(file, param)
+		if !ok && !param.Required {
+			continue
+		}
+		switch param.In {
+		case "query":
+			if cfg.URL.Query == nil {
+				cfg.URL.Query = make(map[string]string)
+			}
+			cfg.URL.Query[param.Name] = value
+		case "header":
+			if !param.Required {
+				continue
+			}
+			if cfg.Headers == nil {
+				cfg.Headers = make(map[string]string)
+			}
+			cfg.Headers[param.Name] = value
+		case "cookie":
+			if param.Required {
+				im.warn(file, "required cookie %s was not generated", param.Name)
+			}
+		}
+	}
+	if err := im.body(file, op, &cfg); err != nil {
+		return err
+	}
+	if assertion := im.status(op); assertion != "" {
+		cfg.Hooks.After.Inline = assertion
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := encodeYAML(buf, &cfg); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// parameter returns the value of a parameter, which is its example if it has one and an interpolation of the property
+// of the same name otherwise. The second return value reports whether an example was found.
+func (im *openapiImporter) parameter(file string, param openapiParameter) (string, bool) {
+	example := &param.Example
+	if example.Kind == 0 {
+		example = im.example(file, &param.Schema, 0, true)
+	}
+	if example == nil || example.Kind != yaml.ScalarNode || example.Tag == "!!null" {
+		return "${props:" + param.Name + "}", false
+	}
+	return example.Value, true
+}
+
+// body fills in an example body for operations which accept JSON.
+func (im *openapiImporter) body(file string, op openapiOperation, cfg *transaction) error {
+	if op.RequestBody.Kind == 0 {
+		return nil
+	}
+	body := im.resolve(file, &op.RequestBody)
+	content := lookup(body, "content")
+	if content == nil || len(content.Content) == 0 {
+		return nil
+	}
+	for i := 0; i+1 < len(content.Content); i += 2 {
+		mediaType := content.Content[i].Value
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			continue
+		}
+		media := content.Content[i+1]
+		example := lookup(media, "example")
+		if examples := lookup(media, "examples"); example == nil && examples != nil && len(examples.Content) > 1 {
+			example = lookup(im.resolve(file, examples.Content[1]), "value")
+		}
+		if example == nil {
+			example = im.example(file, lookup(media, "schema"), 0, false)
+		}
+		if example == nil {
+			return nil
+		}
+		cfg.Body.JSON = *plain(example)
+		if mediaType != "application/json" {
+			if cfg.Headers == nil {
+				cfg.Headers = make(map[string]string)
+			}
+			cfg.Headers["Content-Type"] = mediaType
+		}
+		return nil
+	}
+	im.warn(file, "request body of type %s was not generated", content.Content[0].Value)
+	return nil
+}
+
+// status returns an assertion of the documented success status of an operation, or an empty string if it documents
+// none.
+func (im *openapiImporter) status(op openapiOperation) string {
+	var codes []string
+	for i := 0; i+1 < len(op.Responses.Content); i += 2 {
+		if code := op.Responses.Content[i].Value; strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	slices.Sort(codes)
+	if strings.ToUpper(codes[0]) == "2XX" {
+		return "assert(response.status_code >= 200 and response.status_code < 300, " +
+			"\"expected a successful status but got \" + response.status);\n"
+	}
+	return fmt.Sprintf("assert(response.status_code == %s, \"expected status %s but got \" + response.status);\n",
+		codes[0], codes[0])
+}
+
+// example builds an example value of schema. Explicit examples, defaults and enumerations take precedence over values
+// derived from the type of the schema. When scalar is set only explicit values are considered, since a made up value
+// is of little use for a parameter.
+func (im *openapiImporter) example(file string, schema *yaml.Node, depth int, scalar bool) *yaml.Node {
+	if depth > 16 {
+		// Recursive schemas are cut short rather than expanded indefinitely.
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	schema = im.resolve(file, schema)
+	if schema == nil {
+		return nil
+	}
+	if example := lookup(schema, "example"); example != nil {
+		return example
+	}
+	if examples := lookup(schema, "examples"); examples != nil && examples.Kind == yaml.SequenceNode &&
+		len(examples.Content) > 0 {
+		return examples.Content[0]
+	}
+	if def := lookup(schema, "default"); def != nil {
+		return def
+	}
+	if enum := lookup(schema, "enum"); enum != nil && len(enum.Content) > 0 {
+		return enum.Content[0]
+	}
+	if scalar {
+		return nil
+	}
+	if all := lookup(schema, "allOf"); all != nil {
+		merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, sub := range all.Content {
+			example := im.example(file, sub, depth+1, false)
+			if example == nil || example.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(example.Content); i += 2 {
+				if lookup(merged, example.Content[i].Value) == nil {
+					merged.Content = append(merged.Content, example.Content[i], example.Content[i+1])
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alternatives := lookup(schema, key); alternatives != nil && len(alternatives.Content) > 0 {
+			return im.example(file, alternatives.Content[0], depth+1, false)
+		}
+	}
+	typ := ""
+	if t := lookup(schema, "type"); t != nil {
+		typ = t.Value
+		// OpenAPI 3.1 allows a list of types, in which case the first one that is not null is used.
+		for _, alt := range t.Content {
+			if alt.Value != "null" {
+				typ = alt.Value
+				break
+			}
+		}
+	}
+	switch {
+	case typ == "object" || typ == "" && lookup(schema, "properties") != nil:
+		obj := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if props := lookup(schema, "properties"); props != nil {
+			for i := 0; i+1 < len(props.Content); i += 2 {
+				value := im.example(file, props.Content[i+1], depth+1, false)
+				if value == nil {
+					continue
+				}
+				key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: props.Content[i].Value}
+				obj.Content = append(obj.Content, key, value)
+			}
+		}
+		return obj
+	case typ == "array" || typ == "" && lookup(schema, "items") != nil:
+		arr := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if item := im.example(file, lookup(schema, "items"), depth+1, false); item != nil {
+			arr.Content = append(arr.Content, item)
+		}
+		return arr
+	case typ == "string":
+		value := "string"
+		if format := lookup(schema, "format"); format != nil {
+			switch format.Value {
+			case "date":
+				value = "2006-01-02"
+			case "date-time":
+				value = "2006-01-02T15:04:05Z"
+			case "email":
+				value = "user@example.com"
+			case "uuid":
+				value = "00000000-0000-0000-0000-000000000000"
+			case "uri", "url":
+				value = "https://example.com"
+			}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	case typ == "integer":
+		if minimum := lookup(schema, "minimum"); minimum != nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: minimum.Value}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "0"}
+	case typ == "number":
+		if minimum := lookup(schema, "minimum"); minimum != nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: minimum.Value}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: "0.0"}
+	case typ == "boolean":
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
+
+// resolve follows the reference of node, if it is one, and returns the node it refers to. Only references within the
+// document are supported, other references are flagged and resolve to nil.
+func (im *openapiImporter) resolve(file string, node *yaml.Node) *yaml.Node {
+	for seen := 0; node != nil; seen++ {
+		ref := lookup(node, "$ref")
+		if ref == nil {
+			return node
+		}
+		if !strings.HasPrefix(ref.Value, "#/") || seen > 32 {
+			im.warn(file, "unsupported reference %s", ref.Value)
+			return nil
+		}
+		node = im.root
+		for _, segment := range strings.Split(ref.Value[2:], "/") {
+			segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+			if node = lookup(node, segment); node == nil {
+				im.warn(file, "unresolved reference %s", ref.Value)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// plain returns a deep copy of node without any styling, which lets examples given in JSON be written as block YAML.
+func plain(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return plain(node.Alias)
+	}
+	c := &yaml.Node{Kind: node.Kind, Tag: node.Tag, Value: node.Value}
+	for _, child := range node.Content {
+		c.Content = append(c.Content, plain(child))
+	}
+	return c
+}
+
+// lookup returns the value of key in the mapping node, or nil if node is not a mapping or does not hold key.
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
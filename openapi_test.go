@@ -0,0 +1,186 @@
+package pia_test
+
+import (
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const openapiDocument = `
+openapi: 3.0.3
+info:
+  title: Users API
+  version: 1.0.0
+servers:
+  - url: https://{region}.example.com/v1/
+    variables:
+      region:
+        default: eu
+paths:
+  /users:
+    get:
+      tags: [Users]
+      operationId: listUsers
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            default: 10
+        - name: cursor
+          in: query
+          schema:
+            type: string
+        - $ref: '#/components/parameters/Tenant'
+      responses:
+        '200':
+          description: OK
+    post:
+      tags: [Users]
+      operationId: createUser
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+      responses:
+        '201':
+          description: Created
+        '400':
+          description: Bad request
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    delete:
+      responses:
+        2XX:
+          description: Deleted
+components:
+  parameters:
+    Tenant:
+      name: X-Tenant
+      in: header
+      required: true
+      schema:
+        type: string
+  schemas:
+    User:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          example: Jane Doe
+        email:
+          type: string
+          format: email
+        age:
+          type: integer
+          minimum: 18
+        roles:
+          type: array
+          items:
+            type: string
+            enum: [admin, user]
+        id:
+          type: string
+          format: uuid
+`
+
+func TestImportOpenAPI(t *testing.T) {
+	dir := t.TempDir()
+	warnings, err := pia.ImportOpenAPI(dir, strings.NewReader(openapiDocument))
+	assert.Nil(t, err)
+	assert.Empty(t, warnings)
+
+	expected := map[string]string{
+		pia.ImportedVariables: "base_url=https://eu.example.com/v1\n",
+		filepath.Join("users", "list-users.yml"): `method: GET
+url:
+  target: ${props:base_url}/users
+  query:
+    limit: "10"
+headers:
+  X-Tenant: ${props:X-Tenant}
+hooks:
+  after:
+    inline: |
+      assert(response.status_code == 200, "expected status 200 but got " + response.status);
+`,
+		filepath.Join("users", "create-user.yml"): `method: POST
+url:
+  target: ${props:base_url}/users
+body:
+  json:
+    name: Jane Doe
+    email: user@example.com
+    age: 18
+    roles:
+      - admin
+    id: 00000000-0000-0000-0000-000000000000
+hooks:
+  after:
+    inline: |
+      assert(response.status_code == 201, "expected status 201 but got " + response.status);
+`,
+		"delete-users-id.yml": `method: DELETE
+url:
+  target: ${props:base_url}/users/${props:id}
+hooks:
+  after:
+    inline: |
+      assert(response.status_code >= 200 and response.status_code < 300, "expected a successful status but got " + response.status);
+`,
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		assert.Nil(t, err)
+		assert.Equal(t, content, string(data), name)
+	}
+}
+
+func TestImportOpenAPI_JSON(t *testing.T) {
+	doc := `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/pets": {
+      "post": {
+        "requestBody": {
+          "content": {
+            "application/vnd.pet+json": {"example": {"name": "Rex", "tags": ["dog"]}}
+          }
+        },
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`
+	dir := t.TempDir()
+	warnings, err := pia.ImportOpenAPI(dir, strings.NewReader(doc))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{dir + ": the document lists no servers, base_url must be declared by hand"}, warnings)
+	data, err := os.ReadFile(filepath.Join(dir, "post-pets.yml"))
+	assert.Nil(t, err)
+	assert.Equal(t, `method: POST
+url:
+  target: ${props:base_url}/pets
+headers:
+  Content-Type: application/vnd.pet+json
+body:
+  json:
+    name: Rex
+    tags:
+      - dog
+hooks:
+  after:
+    inline: |
+      assert(response.status_code == 200, "expected status 200 but got " + response.status);
+`, string(data))
+}
@@ -0,0 +1,121 @@
+package pia
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// placeholder is a substitution point of a transaction file, found at Offset bytes from the start of the file.
+type placeholder struct {
+	Key    string
+	Offset int64
+}
+
+// decodeTransaction decodes the transaction configuration read from r before interpolating it, which keeps values from
+// altering the structure of the document. Every substitution point is replaced by a token while the document is
+// tokenized, and the tokens are replaced by the values returned by resolve once the document has been decoded into a
+// tree. Scalars that hold a substitution point keep the type YAML gives to their interpolated value, except those of
+// JSON and YAML bodies, which are always strings unless the scalar is explicitly tagged, e.g. "!!int ${props:port}".
+func decodeTransaction(r io.Reader, resolve func(p placeholder) (string, error)) (transaction, error) {
+	var cfg transaction
+	m := &masker{prefix: "PIA" + rand.Text()}
+	m.ip = WrapReader(m, r)
+	masked, err := io.ReadAll(m.ip)
+	if err != nil {
+		return cfg, err
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(masked)).Decode(&doc); err != nil {
+		return cfg, err
+	}
+	literal := make(map[*yaml.Node]bool)
+	if len(doc.Content) > 0 {
+		if body := lookup(doc.Content[0], "body"); body != nil {
+			literal[lookup(body, "json")] = true
+			literal[lookup(body, "yaml")] = true
+		}
+	}
+	if err := m.substitute(&doc, false, literal, resolve); err != nil {
+		return cfg, err
+	}
+	err = doc.Decode(&cfg)
+	return cfg, err
+}
+
+// masker stands in for a [pia.KeyResolver] while a document is tokenized, resolving every key to a token which is
+// unique within the document.
+type masker struct {
+	prefix string
+	ip     *Interpolator
+	points []placeholder
+}
+
+// tokenDigits is the number of digits of the index of a placeholder following the prefix of a token.
+const tokenDigits = 8
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (m *masker) Resolve(k string) (string, error) {
+	m.points = append(m.points, placeholder{Key: k, Offset: m.ip.start})
+	return fmt.Sprintf("%s%0*d", m.prefix, tokenDigits, len(m.points)-1), nil
+}
+
+// substitute replaces the tokens within the scalars of the tree rooted in node with their values. Scalars within the
+// nodes marked as literal are interpolated as strings.
+func (m *masker) substitute(
+	node *yaml.Node,
+	literal bool,
+	literals map[*yaml.Node]bool,
+	resolve func(p placeholder) (string, error),
+) error {
+	literal = literal || literals[node]
+	if node.Kind != yaml.ScalarNode {
+		for _, c := range node.Content {
+			if err := m.substitute(c, literal, literals, resolve); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !strings.Contains(node.Value, m.prefix) {
+		return nil
+	}
+	var b strings.Builder
+	rest := node.Value
+	for {
+		i := strings.Index(rest, m.prefix)
+		if i < 0 {
+			break
+		}
+		b.WriteString(rest[:i])
+		rest = rest[i+len(m.prefix):]
+		if len(rest) < tokenDigits {
+			return fmt.Errorf("line %d: malformed substitution point", node.Line)
+		}
+		n, err := strconv.Atoi(rest[:tokenDigits])
+		if err != nil || n >= len(m.points) {
+			return fmt.Errorf("line %d: malformed substitution point", node.Line)
+		}
+		v, err := resolve(m.points[n])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		b.WriteString(v)
+		rest = rest[tokenDigits:]
+	}
+	b.WriteString(rest)
+	node.Value = b.String()
+	switch {
+	case node.Style&yaml.TaggedStyle != 0:
+	case literal:
+		node.Style, node.Tag = yaml.DoubleQuotedStyle, "!!str"
+	case node.Style == 0:
+		// Plain scalars are resolved anew, as if their value had been written in place of the substitution point.
+		node.Tag = ""
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+package pia
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The following types represent the subset of the Postman collection format v2.1 that is understood by ImportPostman.
+// See https://schema.postman.com/collection/json/v2.1.0/draft-07/docs/index.html for the full format.
+
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Event    []postmanEvent    `json:"event"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Event   []postmanEvent  `json:"event"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *postmanBody      `json:"body"`
+	Auth   *postmanAuth      `json:"auth"`
+}
+
+// UnmarshalJSON accepts both forms of a request, since a request may be given as a plain URL string.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.URL.Raw); err == nil {
+		r.Method = "GET"
+		return nil
+	}
+	type plain postmanRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Query    []postmanKeyValue `json:"query"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// UnmarshalJSON accepts both forms of a URL, since a URL may be given as a plain string.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &u.Raw); err == nil {
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type postmanKeyValue struct {
+	Key         string       `json:"key"`
+	Value       string       `json:"value"`
+	Disabled    bool         `json:"disabled"`
+	Type        string       `json:"type"`
+	Src         postmanLines `json:"src"`
+	ContentType string       `json:"contentType"`
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	File       struct {
+		Src string `json:"src"`
+	} `json:"file"`
+	GraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Basic  []postmanKeyValue `json:"basic"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	APIKey []postmanKeyValue `json:"apikey"`
+}
+
+func postmanAttribute(attrs []postmanKeyValue, key string) string {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec postmanLines `json:"exec"`
+	} `json:"script"`
+}
+
+// postmanLines is a list of strings which may also be given as a single string.
+type postmanLines []string
+
+func (l *postmanLines) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = postmanLines{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+type postmanEnvironment struct {
+	Name   string `json:"name"`
+	Values []struct {
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		Enabled *bool  `json:"enabled"`
+	} `json:"values"`
+}
+
+// postmanVariable matches Postman placeholders. Names starting with $ denote dynamic variables, such as {{$guid}}, which
+// have no counterpart among the properties and are matched separately.
+var postmanVariable = regexp.MustCompile(`{{\s*([^${}\s][^{}]*?)\s*}}`)
+
+var postmanDynamicVariable = regexp.MustCompile(`{{\s*\$[^{}]*}}`)
+
+// ImportPostman converts a Postman collection (format v2.1) read from r into a tree of transaction files rooted in dir.
+// Folders become directories, each holding a collection manifest that preserves the order of its requests, and requests
+// become transaction files. Postman variables are turned into "${props:name}" interpolations and the variables of the
+// collection itself are written to a property file within dir.
+//
+// Scripts cannot be translated to Squeak, which is why they are written next to the transaction they belong to and
+// flagged by a comment in the transaction file. The returned warnings describe every script and every other part of
+// the collection that could not be translated faithfully.
+func ImportPostman(dir string, r io.Reader) ([]string, error) {
+	var c postmanCollection
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid postman collection: %w", err)
+	}
+	if c.Info.Name == "" && c.Item == nil {
+		return nil, errors.New("invalid postman collection: no info or items")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	im := postmanImporter{}
+	if err := im.scripts(dir, "collection", c.Event); err != nil {
+		return nil, err
+	}
+	if err := im.folder(dir, c.Item, c.Auth); err != nil {
+		return nil, err
+	}
+	if len(c.Variable) > 0 {
+		props := make(map[string]string, len(c.Variable))
+		for _, v := range c.Variable {
+			if !v.Disabled {
+				props[v.Key] = v.Value
+			}
+		}
+		if err := writeProperties(filepath.Join(dir, ImportedVariables), props); err != nil {
+			return nil, err
+		}
+	}
+	return im.warnings, nil
+}
+
+// ImportPostmanEnvironment converts a Postman environment read from r into a property file within dir, named after the
+// environment. The path of the property file is returned.
+func ImportPostmanEnvironment(dir string, r io.Reader) (string, error) {
+	var env postmanEnvironment
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return "", fmt.Errorf("invalid postman environment: %w", err)
+	}
+	props := make(map[string]string, len(env.Values))
+	for _, v := range env.Values {
+		if v.Enabled == nil || *v.Enabled {
+			props[v.Key] = v.Value
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filename(env.Name)+".properties")
+	return path, writeProperties(path, props)
+}
+
+type postmanImporter struct {
+	warnings []string
+}
+
+func (im *postmanImporter) warn(path, format string, args ...any) {
+	im.warnings = append(im.warnings, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (im *postmanImporter) folder(dir string, items []postmanItem, auth *postmanAuth) error {
+	var (
+		transactions []string
+		taken        = make(map[string]bool)
+	)
+	unique := func(name, ext string) string {
+		base := filename(name)
+		candidate := base + ext
+		for i := 2; taken[candidate]; i++ {
+			candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		taken[candidate] = true
+		return candidate
+	}
+	for _, item := range items {
+		inherited := auth
+		if item.Auth != nil {
+			inherited = item.Auth
+		}
+		if item.Request == nil {
+			sub := filepath.Join(dir, unique(item.Name, ""))
+			if err := os.MkdirAll(sub, 0755); err != nil {
+				return err
+			}
+			if err := im.scripts(sub, "folder", item.Event); err != nil {
+				return err
+			}
+			if err := im.folder(sub, item.Item, inherited); err != nil {
+				return err
+			}
+			continue
+		}
+		name := unique(item.Name, ".yml")
+		path := filepath.Join(dir, name)
+		if err := im.request(path, item, inherited); err != nil {
+			return err
+		}
+		transactions = append(transactions, name)
+	}
+	if len(transactions) == 0 {
+		return nil
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := encodeYAML(buf, collection{Transactions: transactions}); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, CollectionManifest), buf.Bytes(), 0644)
+}
+
+// scripts writes the scripts of a collection or a folder to dir and flags them, since they have no counterpart in Pia.
+func (im *postmanImporter) scripts(dir, kind string, events []postmanEvent) error {
+	for _, ev := range events {
+		src := strings.Join(ev.Script.Exec, "\n")
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		script := filepath.Join(dir, ev.Listen+".js")
+		if err := os.WriteFile(script, []byte(src+"\n"), 0644); err != nil {
+			return err
+		}
+		im.warn(dir, "%s %s script was not translated, see %s", kind, ev.Listen, filepath.Base(script))
+	}
+	return nil
+}
+
+func (im *postmanImporter) request(path string, item postmanItem, auth *postmanAuth) error {
+	req := item.Request
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	var cfg transaction
+	cfg.Method = strings.ToUpper(req.Method)
+	if cfg.Method == "" {
+		cfg.Method = "GET"
+	}
+	target, query, _ := strings.Cut(req.URL.Raw, "?")
+	for _, v := range req.URL.Variable {
+		// Path variables, such as :id, are substituted by their value which may itself be a variable.
+		target = pathVariable(target, v.Key, v.Value)
+	}
+	cfg.URL.Target = im.convert(path, target)
+	params := req.URL.Query
+	if params == nil && query != "" {
+		for _, pair := range strings.Split(query, "&") {
+			k, v, _ := strings.Cut(pair, "=")
+			params = append(params, postmanKeyValue{Key: k, Value: v})
+		}
+	}
+	for _, p := range params {
+		if p.Disabled {
+			continue
+		}
+		if cfg.URL.Query == nil {
+			cfg.URL.Query = make(map[string]string)
+		}
+		if _, ok := cfg.URL.Query[p.Key]; ok {
+			im.warn(path, "query parameter %s is given more than once, only the last value is kept", p.Key)
+		}
+		cfg.URL.Query[escapePlaceholders(p.Key)] = im.convert(path, p.Value)
+	}
+	header := func(k, v string) {
+		if cfg.Headers == nil {
+			cfg.Headers = make(map[string]string)
+		}
+		cfg.Headers[k] = v
+	}
+	for _, h := range req.Header {
+		if !h.Disabled {
+			header(escapePlaceholders(h.Key), im.convert(path, h.Value))
+		}
+	}
+	if auth != nil {
+		im.auth(path, auth, &cfg, header)
+	}
+	if req.Body != nil {
+		im.body(path, req.Body, &cfg, header)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, ev := range item.Event {
+		src := strings.Join(ev.Script.Exec, "\n")
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		script := strings.TrimSuffix(path, ".yml") + "." + ev.Listen + ".js"
+		if err := os.WriteFile(script, []byte(src+"\n"), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "# TODO: the Postman %s script of this request could not be translated, see %s\n",
+			ev.Listen, filepath.Base(script))
+		im.warn(path, "%s script was not translated, see %s", ev.Listen, filepath.Base(script))
+	}
+	if err := encodeYAML(buf, &cfg); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// pathVariable replaces the path segments of target that consist of the path variable key, such as :id, with value.
+// Segments merely starting with the variable, such as :idx, are left alone.
+func pathVariable(target, key, value string) string {
+	var b strings.Builder
+	segment := "/:" + key
+	for {
+		i := strings.Index(target, segment)
+		if i < 0 {
+			break
+		}
+		end := i + len(segment)
+		b.WriteString(target[:i])
+		if end == len(target) || strings.IndexByte("/?#", target[end]) != -1 {
+			b.WriteString("/" + value)
+		} else {
+			b.WriteString(segment)
+		}
+		target = target[end:]
+	}
+	b.WriteString(target)
+	return b.String()
+}
+
+func (im *postmanImporter) auth(path string, auth *postmanAuth, cfg *transaction, header func(k, v string)) {
+	switch auth.Type {
+	case "", "noauth":
+	case "bearer":
+		header("Authorization", "Bearer "+im.convert(path, postmanAttribute(auth.Bearer, "token")))
+	case "basic":
+		credentials := postmanAttribute(auth.Basic, "username") + ":" + postmanAttribute(auth.Basic, "password")
+		if postmanVariable.MatchString(credentials) || postmanDynamicVariable.MatchString(credentials) {
+			// The credentials must be encoded after interpolation, which is not possible for a static header.
+			im.warn(path, "basic authentication with variables was not imported")
+			return
+		}
+		header("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "apikey":
+		key := im.convert(path, postmanAttribute(auth.APIKey, "key"))
+		value := im.convert(path, postmanAttribute(auth.APIKey, "value"))
+		if postmanAttribute(auth.APIKey, "in") == "query" {
+			if cfg.URL.Query == nil {
+				cfg.URL.Query = make(map[string]string)
+			}
+			cfg.URL.Query[key] = value
+			return
+		}
+		header(key, value)
+	default:
+		im.warn(path, "%s authentication is not supported", auth.Type)
+	}
+}
+
+func (im *postmanImporter) body(path string, body *postmanBody, cfg *transaction, header func(k, v string)) {
+	contentType := func(v string) {
+		for k := range cfg.Headers {
+			if strings.EqualFold(k, "Content-Type") {
+				return
+			}
+		}
+		header("Content-Type", v)
+	}
+	switch body.Mode {
+	case "raw":
+		cfg.Body.Inline = im.convert(path, body.Raw)
+		switch body.Options.Raw.Language {
+		case "json":
+			contentType("application/json")
+		case "xml":
+			contentType("application/xml")
+		}
+	case "urlencoded":
+		contentType("application/x-www-form-urlencoded")
+		for _, kv := range body.URLEncoded {
+			if kv.Disabled {
+				continue
+			}
+			if cfg.Body.Form == nil {
+				cfg.Body.Form = make(map[string]string)
+			}
+			cfg.Body.Form[escapePlaceholders(kv.Key)] = im.convert(path, kv.Value)
+		}
+	case "formdata":
+		for _, kv := range body.FormData {
+			if kv.Disabled {
+				continue
+			}
+			p := part{Name: escapePlaceholders(kv.Key), ContentType: kv.ContentType}
+			if kv.Type == "file" {
+				if len(kv.Src) != 1 {
+					im.warn(path, "form field %s must reference exactly one file, fill in the file by hand", kv.Key)
+				}
+				if len(kv.Src) > 0 {
+					p.File = kv.Src[0]
+				}
+			} else {
+				p.Value = im.convert(path, kv.Value)
+			}
+			cfg.Body.Multipart = append(cfg.Body.Multipart, p)
+		}
+	case "file":
+		if body.File.Src == "" {
+			im.warn(path, "file body does not reference a file, fill in the file by hand")
+		}
+		cfg.Body.File = body.File.Src
+	case "graphql":
+		doc := map[string]any{"query": body.GraphQL.Query}
+		if strings.TrimSpace(body.GraphQL.Variables) != "" {
+			doc["variables"] = json.RawMessage(body.GraphQL.Variables)
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			im.warn(path, "graphql body was not imported: %s", err)
+			return
+		}
+		cfg.Body.Inline = im.convert(path, string(data))
+		contentType("application/json")
+	case "":
+	default:
+		im.warn(path, "%s body is not supported", body.Mode)
+	}
+}
+
+// convert turns the Postman variables of s into property interpolations. Dynamic variables are left as they are and
+// flagged since Pia has no equivalent. Any "${" already in s is escaped to keep it from being interpolated.
+func (im *postmanImporter) convert(path, s string) string {
+	for _, v := range postmanDynamicVariable.FindAllString(s, -1) {
+		im.warn(path, "dynamic variable %s has no equivalent and was left as is", v)
+	}
+	return postmanVariable.ReplaceAllString(escapePlaceholders(s), "$${props:$1}")
+}
+
+// filename returns a file name derived from name which is safe to use on all platforms.
+func filename(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	s := strings.Trim(b.String(), "-.")
+	if s == "" {
+		return "untitled"
+	}
+	return s
+}
+
+func writeProperties(path string, props map[string]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encodeProperties(f, props); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encodeProperties writes props to w as a property file, one key=value line per property in lexical order. Keys and
+// values are escaped such that ParseProperties reads them back unchanged.
+func encodeProperties(w io.Writer, props map[string]string) error {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", escapeProperty(k, true), escapeProperty(props[k], false)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
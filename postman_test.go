@@ -0,0 +1,154 @@
+package pia_test
+
+import (
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const postmanCollection = `{
+  "info": {
+    "name": "Users API",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {
+    "type": "bearer",
+    "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]
+  },
+  "variable": [{"key": "base_url", "value": "https://example.com"}],
+  "item": [
+    {
+      "name": "Login",
+      "request": {
+        "auth": {"type": "noauth"},
+        "method": "POST",
+        "url": "{{base_url}}/login",
+        "body": {
+          "mode": "urlencoded",
+          "urlencoded": [
+            {"key": "username", "value": "{{ username }}"},
+            {"key": "debug", "value": "true", "disabled": true}
+          ]
+        }
+      },
+      "event": [
+        {"listen": "test", "script": {"exec": ["pm.environment.set('token', pm.response.json().token);"]}}
+      ]
+    },
+    {
+      "name": "Users",
+      "item": [
+        {
+          "name": "Get user",
+          "request": {
+            "method": "GET",
+            "header": [{"key": "Accept", "value": "application/json"}],
+            "url": {
+              "raw": "{{base_url}}/users/:id/:idx/:id?expand=true",
+              "query": [{"key": "expand", "value": "true"}],
+              "variable": [{"key": "id", "value": "{{user_id}}"}, {"key": "idx", "value": "0"}]
+            }
+          }
+        },
+        {
+          "name": "Create user",
+          "request": {
+            "method": "POST",
+            "header": [{"key": "X-Request-Id", "value": "{{$guid}}"}],
+            "url": {"raw": "{{base_url}}/users"},
+            "body": {
+              "mode": "raw",
+              "raw": "{\"name\": \"{{name}}\", \"shell\": \"echo ${HOME}\"}",
+              "options": {"raw": {"language": "json"}}
+            }
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestImportPostman(t *testing.T) {
+	dir := t.TempDir()
+	warnings, err := pia.ImportPostman(dir, strings.NewReader(postmanCollection))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "login.yml") + ": test script was not translated, see login.test.js",
+		filepath.Join(dir, "users", "create-user.yml") + ": dynamic variable {{$guid}} has no equivalent and was left as is",
+	}, warnings)
+
+	expected := map[string]string{
+		"login.yml": `# TODO: the Postman test script of this request could not be translated, see login.test.js
+method: POST
+url:
+  target: ${props:base_url}/login
+headers:
+  Content-Type: application/x-www-form-urlencoded
+body:
+  form:
+    username: ${props:username}
+`,
+		"login.test.js":        "pm.environment.set('token', pm.response.json().token);\n",
+		pia.CollectionManifest: "transactions:\n  - login.yml\n",
+		pia.ImportedVariables:  "base_url=https://example.com\n",
+		filepath.Join("users", "get-user.yml"): `method: GET
+url:
+  target: ${props:base_url}/users/${props:user_id}/0/${props:user_id}
+  query:
+    expand: "true"
+headers:
+  Accept: application/json
+  Authorization: Bearer ${props:token}
+`,
+		filepath.Join("users", "create-user.yml"): `method: POST
+url:
+  target: ${props:base_url}/users
+headers:
+  Authorization: Bearer ${props:token}
+  Content-Type: application/json
+  X-Request-Id: '{{$guid}}'
+body:
+  inline: '{"name": "${props:name}", "shell": "echo $${HOME}"}'
+`,
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		assert.Nil(t, err)
+		assert.Equal(t, content, string(data), name)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "users", "create-user.yml"))
+	assert.Nil(t, err)
+	defer f.Close()
+	tx, err := pia.ParseTransaction(dir, f, pia.WithResolver(pia.DelegatingKeyResolver{
+		Delegates: map[string]pia.KeyResolver{
+			"props": pia.MapResolver{"base_url": "https://example.com", "token": "t", "name": "pia"},
+		},
+	}))
+	assert.Nil(t, err)
+	body, err := io.ReadAll(tx.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name": "pia", "shell": "echo ${HOME}"}`, string(body))
+}
+
+func TestImportPostmanEnvironment(t *testing.T) {
+	env := `{
+  "name": "Production",
+  "values": [
+    {"key": "base_url", "value": "https://example.com", "enabled": true},
+    {"key": "token", "value": "abc", "enabled": false},
+    {"key": "username", "value": "admin"}
+  ]
+}`
+	dir := t.TempDir()
+	path, err := pia.ImportPostmanEnvironment(dir, strings.NewReader(env))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "production.properties"), path)
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "base_url=https://example.com\nusername=admin\n", string(data))
+}
@@ -0,0 +1,304 @@
+package pia
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrMalformedProperties is returned when a property file cannot be parsed. The error states the offending line.
+var ErrMalformedProperties = errors.New("malformed properties")
+
+// PropertyFileExtensions are the file extensions understood by LoadProperties.
+var PropertyFileExtensions = []string{".properties", ".env", ".yml", ".yaml", ".json"}
+
+// LoadProperties parses the property file at path, choosing the format by the extension of the file: .env files are
+// parsed by ParseDotEnv, .yml, .yaml and .json files by ParseStructuredProperties and all other files by
+// ParseProperties.
+func LoadProperties(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	parse := ParseProperties
+	switch ext := filepath.Ext(path); {
+	case ext == ".env" || filepath.Base(path) == ".env":
+		parse = ParseDotEnv
+	case ext == ".yml" || ext == ".yaml" || ext == ".json":
+		parse = ParseStructuredProperties
+	}
+	props, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return props, nil
+}
+
+// ParseProperties parses properties in the format of Java property files. Keys are separated from their values by
+// '=', ':' or whitespace, lines starting with '#' or '!' are comments and lines ending with a backslash continue on the
+// next line. Keys and values may contain the escape sequences \t, \n, \r, \f and \uXXXX, while a backslash followed by
+// any other character stands for that character. A value enclosed in double quotes has the quotes removed, which keeps
+// property files written for earlier versions of pia working; escape the opening quote to keep them.
+func ParseProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scn := bufio.NewScanner(r)
+	n := 0
+	for scn.Scan() {
+		n++
+		start := n
+		line := strings.TrimLeft(scn.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// A line ending with an odd number of backslashes continues on the next line, with the leading whitespace of
+		// the next line discarded.
+		for continues(line) {
+			line = line[:len(line)-1]
+			if !scn.Scan() {
+				break
+			}
+			n++
+			line += strings.TrimLeft(scn.Text(), " \t\f")
+		}
+		key, value := splitProperty(line)
+		value = unquote(value)
+		k, err := unescape(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w: %w", start, ErrMalformedProperties, err)
+		}
+		v, err := unescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w: %w", start, ErrMalformedProperties, err)
+		}
+		props[k] = v
+	}
+	return props, scn.Err()
+}
+
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// unquote removes the double quotes enclosing the raw value s, if any. A closing quote preceded by an odd number of
+// backslashes is escaped and does not count.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' || continues(s[:len(s)-1]) {
+		return s
+	}
+	return s[1 : len(s)-1]
+}
+
+// splitProperty splits a logical line into its raw key and value, leaving escape sequences in place.
+func splitProperty(line string) (string, string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) != -1 {
+			end = i
+			break
+		}
+	}
+	key, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\uXXXX escape: \\%s", s[i:])
+			}
+			code, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uXXXX escape: \\%s", s[i:i+5])
+			}
+			b.WriteRune(rune(code))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// escapeProperty escapes s for use as the key or value of a line in a Java property file.
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case key && strings.ContainsRune("=: ", r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case i == 0 && strings.ContainsRune("#! \"", r):
+			// Leading whitespace of values would otherwise be trimmed, a leading '#' or '!' in keys would turn the
+			// line into a comment and a value enclosed in quotes would lose them.
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ParseDotEnv parses properties in the format of .env files, that is KEY=VALUE lines optionally prefixed by export.
+// Values may be enclosed in double quotes, within which \n, \t, \" and \\ are unescaped, or in single quotes, within
+// which the value is taken literally. Lines starting with '#' are comments, as is anything following " #" on a line
+// with an unquoted value.
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scn := bufio.NewScanner(r)
+	n := 0
+	for scn.Scan() {
+		n++
+		line := strings.TrimSpace(scn.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: %w: expected KEY=VALUE", n, ErrMalformedProperties)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: %w: unterminated double quote", n, ErrMalformedProperties)
+			}
+			value = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(value[1:end])
+		case strings.HasPrefix(value, `'`):
+			end := strings.IndexByte(value[1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: %w: unterminated single quote", n, ErrMalformedProperties)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); i != -1 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		props[key] = value
+	}
+	return props, scn.Err()
+}
+
+// closingQuote returns the index of the double quote closing the string starting at the beginning of s, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseStructuredProperties parses properties from a YAML or JSON document. Nested mappings are flattened into keys
+// joined by dots, and sequences are flattened using the indices of their items, which means that the document
+//
+//	server:
+//	  hosts: [a, b]
+//
+// results in the properties server.hosts.0=a and server.hosts.1=b. Scalars keep their textual representation.
+func ParseStructuredProperties(r io.Reader) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return make(map[string]string), nil
+		}
+		return nil, fmt.Errorf("%w: %w", ErrMalformedProperties, err)
+	}
+	props := make(map[string]string)
+	if len(doc.Content) == 0 {
+		return props, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: %w: document must be a mapping", root.Line, ErrMalformedProperties)
+	}
+	return props, flatten(props, "", root)
+}
+
+func flatten(props map[string]string, prefix string, node *yaml.Node) error {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch node.Kind {
+	case yaml.AliasNode:
+		return flatten(props, prefix, node.Alias)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := flatten(props, join(node.Content[i].Value), node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := flatten(props, join(strconv.Itoa(i)), item); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			props[prefix] = ""
+			return nil
+		}
+		props[prefix] = node.Value
+	default:
+		return fmt.Errorf("line %d: %w: unsupported value", node.Line, ErrMalformedProperties)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package pia
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected map[string]string
+	}{
+		{
+			name:     "separators",
+			src:      "a=1\nb:2\nc 3\nd = 4\n  e\t:\t5\nf\n",
+			expected: map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": ""},
+		},
+		{
+			name:     "comments and blank lines",
+			src:      "# comment\n! comment\n\n   \n  # indented comment\nkey=value # not a comment\n",
+			expected: map[string]string{"key": "value # not a comment"},
+		},
+		{
+			name:     "continuations",
+			src:      "fruits=apple, \\\n        banana, \\\n        pear\nnext=value\n",
+			expected: map[string]string{"fruits": "apple, banana, pear", "next": "value"},
+		},
+		{
+			name:     "escaped trailing backslash",
+			src:      "path=C:\\\\\nnext=value\n",
+			expected: map[string]string{"path": `C:\`, "next": "value"},
+		},
+		{
+			name:     "escapes",
+			src:      "tab=a\\tb\nnewline=a\\nb\nunicode=caf\\u00e9\nquote=\\\"q\\\"\nkey\\ with\\:separators=value\n",
+			expected: map[string]string{"tab": "a\tb", "newline": "a\nb", "unicode": "café", "quote": `"q"`, "key with:separators": "value"},
+		},
+		{
+			name: "enclosing quotes are removed",
+			src:  "host=\"example.com\"\nescaped=\\\"q\"\nsingle=\"\nunbalanced=\"a\nclosing=\"a\\\"\n",
+			expected: map[string]string{
+				"host":       "example.com",
+				"escaped":    `"q"`,
+				"single":     `"`,
+				"unbalanced": `"a`,
+				"closing":    `"a"`,
+			},
+		},
+		{
+			name:     "separators in values",
+			src:      "url=http://localhost:8080/?a=b\n",
+			expected: map[string]string{"url": "http://localhost:8080/?a=b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			props, err := ParseProperties(strings.NewReader(test.src))
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, props)
+		})
+	}
+}
+
+func TestParseProperties_Error(t *testing.T) {
+	_, err := ParseProperties(strings.NewReader("a=1\n\nb=\\u12\n"))
+	assert.True(t, errors.Is(err, ErrMalformedProperties))
+	assert.ErrorContains(t, err, "line 3")
+}
+
+func TestParseDotEnv(t *testing.T) {
+	src := strings.Join([]string{
+		"# comment",
+		"PLAIN=value",
+		"export EXPORTED=yes",
+		"SPACED = padded   ",
+		`DOUBLE="a \"quoted\"\nvalue"`,
+		`SINGLE='literal \n $value'`,
+		"COMMENTED=value # comment",
+		"EMPTY=",
+	}, "\n")
+	props, err := ParseDotEnv(strings.NewReader(src))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"PLAIN":     "value",
+		"EXPORTED":  "yes",
+		"SPACED":    "padded",
+		"DOUBLE":    "a \"quoted\"\nvalue",
+		"SINGLE":    `literal \n $value`,
+		"COMMENTED": "value",
+		"EMPTY":     "",
+	}, props)
+}
+
+func TestParseDotEnv_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line string
+	}{
+		{name: "missing separator", src: "A=1\nB\n", line: "line 2"},
+		{name: "unterminated double quote", src: "A=\"1\n", line: "line 1"},
+		{name: "unterminated single quote", src: "\nA='1\n", line: "line 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDotEnv(strings.NewReader(test.src))
+			assert.True(t, errors.Is(err, ErrMalformedProperties))
+			assert.ErrorContains(t, err, test.line)
+		})
+	}
+}
+
+func TestParseStructuredProperties(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{name: "yaml", src: "host: example.com\nport: 8080\nretry: 1.50\nserver:\n  hosts: [a, b]\n  tls: true\nempty: null\n"},
+		{name: "json", src: `{"host": "example.com", "port": 8080, "retry": 1.50, "server": {"hosts": ["a", "b"], "tls": true}, "empty": null}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			props, err := ParseStructuredProperties(strings.NewReader(test.src))
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{
+				"host":           "example.com",
+				"port":           "8080",
+				"retry":          "1.50",
+				"server.hosts.0": "a",
+				"server.hosts.1": "b",
+				"server.tls":     "true",
+				"empty":          "",
+			}, props)
+		})
+	}
+}
+
+func TestParseStructuredProperties_Error(t *testing.T) {
+	_, err := ParseStructuredProperties(strings.NewReader("- a\n- b\n"))
+	assert.True(t, errors.Is(err, ErrMalformedProperties))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestLoadProperties(t *testing.T) {
+	wd := t.TempDir()
+	files := map[string]string{
+		"app.properties": "host: example.com\n",
+		".env":           "host=example.com\n",
+		"app.env":        "export host=example.com\n",
+		"app.yml":        "host: example.com\n",
+		"app.yaml":       "host: example.com\n",
+		"app.json":       `{"host": "example.com"}`,
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, name), []byte(content), 0666))
+		t.Run(name, func(t *testing.T) {
+			props, err := LoadProperties(filepath.Join(wd, name))
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"host": "example.com"}, props)
+		})
+	}
+	path := filepath.Join(wd, "broken.properties")
+	assert.Nil(t, os.WriteFile(path, []byte("key=\\uzzzz\n"), 0666))
+	_, err := LoadProperties(path)
+	assert.ErrorContains(t, err, path+": line 1")
+}
+
+func TestEncodeProperties(t *testing.T) {
+	props := map[string]string{
+		"plain":         "value",
+		"key with=sep:": "  leading space",
+		"#comment":      `C:\path`,
+		"multiline":     "a\nb\tc",
+		"empty":         "",
+		"quoted":        `"value"`,
+	}
+	var b strings.Builder
+	assert.Nil(t, encodeProperties(&b, props))
+	parsed, err := ParseProperties(strings.NewReader(b.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, props, parsed)
+}
@@ -0,0 +1,206 @@
+package pia
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/ernilsson/pia/squeak"
+	"io"
+	"time"
+)
+
+// TestResult is the outcome of a single named check made by a hook of a transaction.
+type TestResult struct {
+	Name    string
+	File    string
+	Passed  bool
+	Message string
+	// Duration is zero unless the check was timed, which only test blocks are.
+	Duration time.Duration
+	// Skipped is set for results standing in for transactions that were never executed, which neither pass nor fail.
+	Skipped bool
+}
+
+// NewTestResults attributes the results recorded by a Squeak interpreter to the transaction file they originate from.
+func NewTestResults(file string, results []squeak.TestResult) []TestResult {
+	converted := make([]TestResult, 0, len(results))
+	for _, r := range results {
+		converted = append(converted, TestResult{
+			Name:     r.Name,
+			File:     file,
+			Passed:   r.Passed,
+			Message:  r.Message,
+			Duration: r.Duration,
+		})
+	}
+	return converted
+}
+
+// NewErrorResult returns a failed TestResult representing an error that prevented a transaction from completing, such
+// as a transport error. Failed assertions are not considered by this function since they are already recorded as test
+// results by the interpreter, in which case the second return value is false.
+func NewErrorResult(file string, err error) (TestResult, bool) {
+	if err == nil || errors.Is(err, squeak.ErrFailedAssertion) {
+		return TestResult{}, false
+	}
+	return TestResult{
+		Name:    "execute",
+		File:    file,
+		Passed:  false,
+		Message: err.Error(),
+	}, true
+}
+
+// Report is a collection of test results which can be written in formats understood by CI systems.
+type Report []TestResult
+
+// Failed returns the number of results that did not pass, not counting skipped results.
+func (r Report) Failed() int {
+	n := 0
+	for _, result := range r {
+		if !result.Passed && !result.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// Skipped returns the number of skipped results.
+func (r Report) Skipped() int {
+	n := 0
+	for _, result := range r {
+		if result.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteJSON writes the report as a single JSON document. Durations are left out of results that were not timed.
+func (r Report) WriteJSON(w io.Writer) error {
+	type result struct {
+		Name       string  `json:"name"`
+		File       string  `json:"file"`
+		Passed     bool    `json:"passed"`
+		Message    string  `json:"message,omitempty"`
+		DurationMS float64 `json:"duration_ms,omitempty"`
+		Skipped    bool    `json:"skipped,omitempty"`
+	}
+	doc := struct {
+		Tests   int      `json:"tests"`
+		Passed  int      `json:"passed"`
+		Failed  int      `json:"failed"`
+		Skipped int      `json:"skipped"`
+		Results []result `json:"results"`
+	}{
+		Tests:   len(r),
+		Passed:  len(r) - r.Failed() - r.Skipped(),
+		Failed:  r.Failed(),
+		Skipped: r.Skipped(),
+		Results: make([]result, 0, len(r)),
+	}
+	for _, res := range r {
+		doc.Results = append(doc.Results, result{
+			Name:       res.Name,
+			File:       res.File,
+			Passed:     res.Passed,
+			Message:    res.Message,
+			DurationMS: float64(res.Duration) / float64(time.Millisecond),
+			Skipped:    res.Skipped,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	Time      string          `xml:"time,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr,omitempty"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// WriteJUnit writes the report as JUnit XML. Each transaction file becomes a test suite holding the results that
+// originate from it, in the order the files first appear in the report. The time attribute is left out of test cases
+// that were not timed, as well as out of suites in which no test case was.
+func (r Report) WriteJUnit(w io.Writer) error {
+	seconds := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", d.Seconds())
+	}
+	doc := junitTestSuites{
+		Tests:    len(r),
+		Failures: r.Failed(),
+		Skipped:  r.Skipped(),
+	}
+	var total time.Duration
+	suites := make(map[string]int)
+	durations := make(map[string]time.Duration)
+	for _, res := range r {
+		i, ok := suites[res.File]
+		if !ok {
+			i = len(doc.Suites)
+			suites[res.File] = i
+			doc.Suites = append(doc.Suites, junitTestSuite{Name: res.File})
+		}
+		tc := junitTestCase{
+			Name:      res.Name,
+			Classname: res.File,
+			Time:      seconds(res.Duration),
+		}
+		switch {
+		case res.Skipped:
+			tc.Skipped = &struct{}{}
+			doc.Suites[i].Skipped++
+		case !res.Passed:
+			tc.Failure = &junitFailure{Message: res.Message, Text: res.Message}
+			doc.Suites[i].Failures++
+		}
+		doc.Suites[i].Tests++
+		doc.Suites[i].TestCases = append(doc.Suites[i].TestCases, tc)
+		durations[res.File] += res.Duration
+		total += res.Duration
+	}
+	for i := range doc.Suites {
+		doc.Suites[i].Time = seconds(durations[doc.Suites[i].Name])
+	}
+	doc.Time = seconds(total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,75 @@
+package pia_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ernilsson/pia"
+	"github.com/ernilsson/pia/squeak"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewErrorResult(t *testing.T) {
+	_, ok := pia.NewErrorResult("login.yml", nil)
+	assert.False(t, ok)
+	_, ok = pia.NewErrorResult("login.yml", squeak.ErrFailedAssertion)
+	assert.False(t, ok)
+	res, ok := pia.NewErrorResult("login.yml", errors.New("connection refused"))
+	assert.True(t, ok)
+	assert.Equal(t, pia.TestResult{
+		Name:    "execute",
+		File:    "login.yml",
+		Message: "connection refused",
+	}, res)
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	report := pia.Report(pia.NewTestResults("login.yml", []squeak.TestResult{
+		{Name: "status is 200", Passed: true, Duration: 1500 * time.Millisecond},
+		{Name: "token is present", Message: "assertion failed: token is present"},
+	}))
+	report = append(report, pia.TestResult{Name: "status is 200", File: "users.yml", Passed: true})
+	report = append(report, pia.TestResult{Name: "execute", File: "roles.yml", Skipped: true})
+	buf := bytes.NewBufferString("")
+	assert.Nil(t, report.WriteJUnit(buf))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1" skipped="1" time="1.500">
+  <testsuite name="login.yml" tests="2" failures="1" time="1.500">
+    <testcase name="status is 200" classname="login.yml" time="1.500"></testcase>
+    <testcase name="token is present" classname="login.yml">
+      <failure message="assertion failed: token is present">assertion failed: token is present</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="users.yml" tests="1" failures="0">
+    <testcase name="status is 200" classname="users.yml"></testcase>
+  </testsuite>
+  <testsuite name="roles.yml" tests="1" failures="0" skipped="1">
+    <testcase name="execute" classname="roles.yml">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	report := pia.Report{
+		{Name: "status is 200", File: "login.yml", Passed: true, Duration: 2 * time.Millisecond},
+		{Name: "token is present", File: "login.yml", Message: "assertion failed"},
+		{Name: "execute", File: "users.yml", Skipped: true},
+	}
+	buf := bytes.NewBufferString("")
+	assert.Nil(t, report.WriteJSON(buf))
+	assert.JSONEq(t, `{
+		"tests": 3,
+		"passed": 1,
+		"failed": 1,
+		"skipped": 1,
+		"results": [
+			{"name": "status is 200", "file": "login.yml", "passed": true, "duration_ms": 2},
+			{"name": "token is present", "file": "login.yml", "passed": false, "message": "assertion failed"},
+			{"name": "execute", "file": "users.yml", "passed": false, "skipped": true}
+		]
+	}`, buf.String())
+}
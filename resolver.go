@@ -11,28 +11,81 @@ type DelegatingKeyResolver struct {
 }
 
 func (d DelegatingKeyResolver) Resolve(k string) (string, error) {
-	sections := strings.SplitN(k, ":", 2)
-	delegate, ok := d.Delegates[sections[0]]
+	name, key, ok := strings.Cut(k, ":")
 	if !ok {
-		return "", fmt.Errorf("%w: %s is not a valid delegate", ErrKeyNotFound, sections[0])
+		return "", fmt.Errorf("%w: %s does not start with a context key", ErrKeyNotFound, k)
+	}
+	delegate, ok := d.Delegates[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not a valid delegate", ErrKeyNotFound, name)
+	}
+	return delegate.Resolve(key)
+}
+
+// Reset implements the [pia.Resetter] interface by resetting every delegate that implements it.
+func (d DelegatingKeyResolver) Reset() {
+	for _, delegate := range d.Delegates {
+		Reset(delegate)
 	}
-	return delegate.Resolve(sections[1])
 }
 
+// FallbackResolverDecorator resolves the modifiers that may follow a key, each introduced by a pipe. Trailing modifiers
+// naming one of the [pia.Filters] are applied to the value in order, while the text between the key and the filters is
+// a default used whenever the key cannot be resolved. The default may itself hold substitution points, which are only
+// resolved if the default is used. For example, "props:host|${env:HOST|localhost}|lower" resolves to the lowercase host
+// property, falling back on the HOST environment variable and finally on localhost.
 type FallbackResolverDecorator struct {
 	Delegate KeyResolver
 }
 
 func (f FallbackResolverDecorator) Resolve(k string) (string, error) {
-	key, fb, ok := strings.Cut(k, "|")
-	if v, err := f.Delegate.Resolve(key); err != nil {
-		if ok {
-			return fb, nil
+	segments := modifiers(k)
+	end := len(segments)
+	for end > 1 {
+		if _, ok := Filters[segments[end-1]]; !ok {
+			break
+		}
+		end--
+	}
+	v, err := f.Delegate.Resolve(segments[0])
+	if err != nil {
+		if end == 1 {
+			return "", err
+		}
+		if v, err = interpolate(f, strings.Join(segments[1:end], "|")); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range segments[end:] {
+		if v, err = Filters[name](v); err != nil {
+			return "", fmt.Errorf("filter %s failed: %w", name, err)
+		}
+	}
+	return v, nil
+}
+
+// modifiers splits k on every pipe that is not part of a nested substitution point.
+func modifiers(k string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(k); i++ {
+		switch {
+		case k[i] == '$' && i+1 < len(k) && k[i+1] == '{':
+			depth++
+			i++
+		case k[i] == '}' && depth > 0:
+			depth--
+		case k[i] == '|' && depth == 0:
+			segments = append(segments, k[start:i])
+			start = i + 1
 		}
-		return "", err
-	} else {
-		return v, nil
 	}
+	return append(segments, k[start:])
+}
+
+// Reset implements the [pia.Resetter] interface by resetting the delegate if it implements it.
+func (f FallbackResolverDecorator) Reset() {
+	Reset(f.Delegate)
 }
 
 type EnvironmentResolver struct{}
@@ -52,6 +105,19 @@ type KeyResolver interface {
 	Resolve(k string) (string, error)
 }
 
+// Resetter is implemented by key resolvers holding state that is scoped to a single transaction, such as pinned
+// generated values. Resolvers are reset before every transaction is interpolated.
+type Resetter interface {
+	Reset()
+}
+
+// Reset resets resolver if it implements [pia.Resetter].
+func Reset(resolver KeyResolver) {
+	if r, ok := resolver.(Resetter); ok {
+		r.Reset()
+	}
+}
+
 // MapResolver is the simplest possible implementation of [pia.KeyResolver] using an underlying map to facilitate
 // storage and retrieval.
 type MapResolver map[string]string
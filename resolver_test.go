@@ -69,3 +69,55 @@ func TestDelegatingKeyResolver_Resolve(t *testing.T) {
 		})
 	}
 }
+
+func TestFallbackResolverDecorator_Resolve(t *testing.T) {
+	resolver := pia.FallbackResolverDecorator{
+		Delegate: pia.DelegatingKeyResolver{
+			Delegates: map[string]pia.KeyResolver{
+				"props": pia.MapResolver{
+					"name":  "Pia Tester",
+					"query": "a b&c=d",
+					"quote": "say \"hi\"\n<now>",
+					"space": "  padded  ",
+					"host":  "EXAMPLE.COM",
+				},
+				"env": pia.MapResolver{
+					"HOST": "env.example.com",
+				},
+			},
+		},
+	}
+	tests := []struct {
+		key   string
+		value string
+		err   error
+	}{
+		{key: "props:name", value: "Pia Tester"},
+		{key: "props:missing|default", value: "default"},
+		{key: "props:missing|", value: ""},
+		{key: "props:missing|a|b", value: "a|b"},
+		{key: "props:name|${env:MISSING}", value: "Pia Tester"},
+		{key: "props:missing|${env:HOST}", value: "env.example.com"},
+		{key: "props:missing|${props:other|${env:HOST}}", value: "env.example.com"},
+		{key: "props:missing|http://${env:HOST}/", value: "http://env.example.com/"},
+		{key: "props:missing|${env:MISSING}", err: pia.ErrKeyNotFound},
+		{key: "props:missing", err: pia.ErrKeyNotFound},
+		{key: "props:query|urlencode", value: "a+b%26c%3Dd"},
+		{key: "props:name|base64", value: "UGlhIFRlc3Rlcg=="},
+		{key: "props:quote|jsonescape", value: `say \"hi\"\n<now>`},
+		{key: "props:name|upper", value: "PIA TESTER"},
+		{key: "props:host|lower", value: "example.com"},
+		{key: "props:space|trim", value: "padded"},
+		{key: "props:name|sha256", value: "e61be2de61da0577ac761f67af16673991d259671f22833651bec6c37222c5eb"},
+		{key: "props:space|trim|upper|base64", value: "UEFEREVE"},
+		{key: "props:missing|${env:HOST|upper}|upper|urlencode", value: "ENV.EXAMPLE.COM"},
+		{key: "props:missing|Mixed Case|lower", value: "mixed case"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			value, err := resolver.Resolve(test.key)
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.value, value)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package pia
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings is a breakdown of the time spent on the different phases of a transaction. Phases that did not take place,
+// such as the TLS handshake of a plain text request or connecting when an idle connection is reused, are zero.
+type Timings struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration
+	Total     time.Duration
+}
+
+// Response is an [http.Response] decorated with metadata gathered while executing a Transaction. Its body has already
+// been read in full, which means that Total in Timings covers the transfer of the entire body.
+type Response struct {
+	*http.Response
+	Timings    Timings
+	Size       int64
+	RemoteAddr string
+
+	body []byte
+}
+
+// tracer gathers timings and connection details through an [httptrace.ClientTrace]. The callbacks of the trace may be
+// invoked concurrently, hence the mutex.
+type tracer struct {
+	mu         sync.Mutex
+	start      time.Time
+	dnsStart   time.Time
+	dns        time.Duration
+	connStart  time.Time
+	connect    time.Duration
+	tlsStart   time.Time
+	tls        time.Duration
+	firstByte  time.Duration
+	remoteAddr string
+}
+
+func (t *tracer) trace() *httptrace.ClientTrace {
+	lock := func(fn func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		fn()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			lock(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			lock(func() { t.dns = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			lock(func() { t.connStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			lock(func() { t.connect = time.Since(t.connStart) })
+		},
+		TLSHandshakeStart: func() {
+			lock(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			lock(func() { t.tls = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			lock(func() { t.remoteAddr = info.Conn.RemoteAddr().String() })
+		},
+		GotFirstResponseByte: func() {
+			lock(func() { t.firstByte = time.Since(t.start) })
+		},
+	}
+}
+
+// response reads the body of res in full and returns it decorated with the gathered metadata. The body of the returned
+// response can be read again by the caller.
+func (t *tracer) response(res *http.Response) (*Response, error) {
+	var body []byte
+	if res.Body != nil {
+		var err error
+		body, err = io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		res.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	total := time.Since(t.start)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &Response{
+		Response: res,
+		Timings: Timings{
+			DNS:       t.dns,
+			Connect:   t.connect,
+			TLS:       t.tls,
+			FirstByte: t.firstByte,
+			Total:     total,
+		},
+		Size:       int64(len(body)),
+		RemoteAddr: t.remoteAddr,
+		body:       body,
+	}, nil
+}
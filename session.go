@@ -0,0 +1,59 @@
+package pia
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NewSession returns an empty [pia.Session] ready for use.
+func NewSession() *Session {
+	return &Session{
+		values: make(map[string]string),
+	}
+}
+
+// Session is a mutable key-value store which lives for as long as the application using it. It implements
+// [pia.KeyResolver] so that it can be registered as a property source for interpolation, while Squeak scripts are
+// expected to mutate it through the session builtin object. It is safe for concurrent use.
+type Session struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (s *Session) Resolve(k string) (string, error) {
+	v, ok := s.Get(k)
+	if !ok {
+		return "", fmt.Errorf("failed to resolve key '%s' from session: %w", k, ErrKeyNotFound)
+	}
+	return v, nil
+}
+
+// Get returns the value stored for k and reports whether it was present in the session.
+func (s *Session) Get(k string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[k]
+	return v, ok
+}
+
+// Set stores v under k, replacing any previous value.
+func (s *Session) Set(k, v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[k] = v
+}
+
+// Delete removes k from the session. Deleting a key that does not exist is a no-op.
+func (s *Session) Delete(k string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, k)
+}
+
+// Clear removes all values from the session.
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.values)
+}
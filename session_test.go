@@ -0,0 +1,43 @@
+package pia_test
+
+import (
+	"github.com/ernilsson/pia"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSession_Resolve(t *testing.T) {
+	session := pia.NewSession()
+	_, err := session.Resolve("id_token")
+	assert.ErrorIs(t, err, pia.ErrKeyNotFound)
+
+	session.Set("id_token", "abc")
+	v, err := session.Resolve("id_token")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", v)
+
+	session.Delete("id_token")
+	_, err = session.Resolve("id_token")
+	assert.ErrorIs(t, err, pia.ErrKeyNotFound)
+
+	session.Set("id_token", "abc")
+	session.Set("refresh_token", "def")
+	session.Clear()
+	_, ok := session.Get("id_token")
+	assert.False(t, ok)
+	_, ok = session.Get("refresh_token")
+	assert.False(t, ok)
+}
+
+func TestSession_DelegatingKeyResolver(t *testing.T) {
+	session := pia.NewSession()
+	resolver := pia.DelegatingKeyResolver{
+		Delegates: map[string]pia.KeyResolver{
+			"session": session,
+		},
+	}
+	session.Set("id_token", "abc")
+	v, err := resolver.Resolve("session:id_token")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", v)
+}
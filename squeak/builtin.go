@@ -1,7 +1,11 @@
 package squeak
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 )
 
 type PrintBuiltin struct{}
@@ -15,7 +19,7 @@ func (p PrintBuiltin) Arity() int {
 }
 
 func (p PrintBuiltin) Call(in *Interpreter, args ...Object) (Object, error) {
-	_, err := fmt.Fprint(in.out, args[0].String())
+	_, err := fmt.Fprint(in.out, str(args[0]))
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +45,7 @@ func (p PrintlnBuiltin) Arity() int {
 }
 
 func (p PrintlnBuiltin) Call(in *Interpreter, args ...Object) (Object, error) {
-	_, err := fmt.Fprintln(in.out, args[0].String())
+	_, err := fmt.Fprintln(in.out, str(args[0]))
 	if err != nil {
 		return nil, err
 	}
@@ -124,10 +128,126 @@ func (a AssertBuiltin) Arity() int {
 	return 2
 }
 
+// Call records the outcome of the assertion as a [squeak.TestResult] named by the message before returning, unless it
+// is made within a test block. A failed assertion results in an error wrapping [squeak.ErrFailedAssertion].
 func (a AssertBuiltin) Call(in *Interpreter, args ...Object) (Object, error) {
 	val := Boolean{in.truthy(args[0])}
-	if val.value {
-		return nil, nil
+	result := TestResult{Name: "assertion", Passed: val.value}
+	if args[1] != nil {
+		result.Name = args[1].String()
 	}
-	return nil, fmt.Errorf("%w: %s", ErrFailedAssertion, args[1])
+	var err error
+	if !val.value {
+		err = fmt.Errorf("%w: %s", ErrFailedAssertion, args[1])
+		result.Message = err.Error()
+	}
+	if in.testing == 0 {
+		in.record(result)
+	}
+	return nil, err
+}
+
+type TestBuiltin struct{}
+
+func (t TestBuiltin) String() string {
+	return "builtin:test"
+}
+
+func (t TestBuiltin) Clone() Object {
+	return TestBuiltin{}
+}
+
+func (t TestBuiltin) Arity() int {
+	return 2
+}
+
+// Call runs the supplied function as a named test block and records its outcome as a [squeak.TestResult]. Failed
+// assertions and runtime errors within the function fail the test but do not abort the script, instead the call
+// evaluates to a boolean stating whether the test passed.
+func (t TestBuiltin) Call(in *Interpreter, args ...Object) (Object, error) {
+	name, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("%w: test name must be a string", ErrIllegalArgument)
+	}
+	var fn Callable
+	switch body := args[1].(type) {
+	case Callable:
+		fn = body
+	case Method:
+		// Anonymous functions are evaluated as methods, which are bound to an empty object to make them callable.
+		bound, err := body.Bind(&ObjectInstance{Properties: make(map[string]Object)})
+		if err != nil {
+			return nil, err
+		}
+		fn = bound
+	}
+	if fn == nil || fn.Arity() != 0 {
+		return nil, fmt.Errorf("%w: test body must be a function without parameters", ErrIllegalArgument)
+	}
+	result := TestResult{Name: name.value}
+	start := time.Now()
+	err := in.test(fn)
+	result.Duration = time.Since(start)
+	result.Passed = err == nil
+	if err != nil {
+		result.Message = err.Error()
+	}
+	in.record(result)
+	return Boolean{result.Passed}, nil
+}
+
+type ValidateBuiltin struct{}
+
+func (v ValidateBuiltin) String() string {
+	return "builtin:validate"
+}
+
+func (v ValidateBuiltin) Clone() Object {
+	return ValidateBuiltin{}
+}
+
+func (v ValidateBuiltin) Arity() int {
+	return 2
+}
+
+// Call validates the second argument against a JSON Schema, given either as an object or as the path of a JSON file
+// relative to the working directory of the interpreter. It evaluates to a list of violations, each an object holding
+// the JSON pointer to the offending value as path along with a message. An empty list means that the value is valid.
+func (v ValidateBuiltin) Call(in *Interpreter, args ...Object) (Object, error) {
+	var schema any
+	switch s := args[0].(type) {
+	case String:
+		path := s.value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(in.wd, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("%w: invalid schema %s: %w", ErrIllegalArgument, s.value, err)
+		}
+	default:
+		var err error
+		if schema, err = native(s); err != nil {
+			return nil, err
+		}
+	}
+	value, err := native(args[1])
+	if err != nil {
+		return nil, err
+	}
+	violations, err := Validate(schema, value)
+	if err != nil {
+		return nil, err
+	}
+	list := &List{slice: make([]Object, 0, len(violations))}
+	for _, violation := range violations {
+		list.slice = append(list.slice, &ObjectInstance{Properties: map[string]Object{
+			"path":    String{violation.Path},
+			"message": String{violation.Message},
+		}})
+	}
+	return list, nil
 }
@@ -1,7 +1,10 @@
 package squeak
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -9,6 +12,108 @@ func TestPrintBuiltin_Arity(t *testing.T) {
 	assert.Equal(t, 1, PrintBuiltin{}.Arity())
 }
 
+func TestPrintBuiltin_Call_Null(t *testing.T) {
+	program, err := ParseString(`
+	var user = json.parse(payload);
+	print(user.name);
+	println(user.name);
+	`)
+	assert.Nil(t, err)
+	out := bytes.NewBufferString("")
+	in := NewInterpreter("", out)
+	in.Declare("payload", String{`{"name": null}`})
+	assert.Nil(t, in.Execute(program))
+	assert.Equal(t, "nilnil\n", out.String())
+}
+
 func TestLengthBuiltin_Arity(t *testing.T) {
 	assert.Equal(t, 1, LengthBuiltin{}.Arity())
 }
+
+func TestAssertBuiltin_Call(t *testing.T) {
+	in := NewInterpreter("", nil)
+	_, err := AssertBuiltin{}.Call(in, Boolean{true}, String{"status is 200"})
+	assert.Nil(t, err)
+	_, err = AssertBuiltin{}.Call(in, Boolean{false}, String{"body is empty"})
+	assert.ErrorIs(t, err, ErrFailedAssertion)
+	assert.Equal(t, []TestResult{
+		{
+			Name:   "status is 200",
+			Passed: true,
+		},
+		{
+			Name:    "body is empty",
+			Passed:  false,
+			Message: "runtime error: assertion failed: body is empty",
+		},
+	}, in.DrainResults())
+	assert.Empty(t, in.DrainResults())
+}
+
+func TestTestBuiltin_Call(t *testing.T) {
+	src := `
+	test("passing", function() {
+		assert(true, "always true");
+	});
+	test("failing assertion", function() {
+		assert(false, "always false");
+	});
+	function fails() {
+		return 1 / 0;
+	}
+	test("runtime error", fails);
+	test("panic", function() {
+		panic("oh no");
+	});
+	println("still running");
+	`
+	program, err := ParseString(src)
+	assert.Nil(t, err)
+	out := bytes.NewBufferString("")
+	in := NewInterpreter("", out)
+	assert.Nil(t, in.Execute(program))
+	assert.Equal(t, "still running\n", out.String())
+	results := in.DrainResults()
+	assert.Len(t, results, 4)
+	assert.Equal(t, "passing", results[0].Name)
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, "failing assertion", results[1].Name)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, "runtime error: assertion failed: always false", results[1].Message)
+	assert.Equal(t, "runtime error", results[2].Name)
+	assert.False(t, results[2].Passed)
+	assert.Equal(t, "runtime error: illegal argument: division by zero", results[2].Message)
+	assert.Equal(t, "panic", results[3].Name)
+	assert.False(t, results[3].Passed)
+	assert.Equal(t, "runtime error: oh no", results[3].Message)
+}
+
+func TestTestBuiltin_Call_IllegalArgument(t *testing.T) {
+	in := NewInterpreter("", nil)
+	_, err := TestBuiltin{}.Call(in, String{"name"}, String{"not a function"})
+	assert.ErrorIs(t, err, ErrIllegalArgument)
+	_, err = TestBuiltin{}.Call(in, Number{1}, PrintBuiltin{})
+	assert.ErrorIs(t, err, ErrIllegalArgument)
+}
+
+func TestValidateBuiltin_Call(t *testing.T) {
+	wd := t.TempDir()
+	schema := `{"type": "object", "required": ["name"], "properties": {"age": {"type": "integer"}}}`
+	assert.Nil(t, os.WriteFile(filepath.Join(wd, "user.schema.json"), []byte(schema), 0666))
+	src := `
+	var user = Object {
+		age: "27"
+	};
+	var violations = validate("user.schema.json", user);
+	println(violations.length());
+	println(violations[0].path + " " + violations[0].message);
+	println(violations[1].path + " " + violations[1].message);
+	println(validate(Object { type: "string" }, "crookdc").length());
+	`
+	program, err := ParseString(src)
+	assert.Nil(t, err)
+	out := bytes.NewBufferString("")
+	in := NewInterpreter(wd, out)
+	assert.Nil(t, in.Execute(program))
+	assert.Equal(t, "2.\n missing required property \"name\"\n/age expected integer but got string\n0.\n", out.String())
+}
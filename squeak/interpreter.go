@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 )
 
 var (
@@ -112,6 +113,9 @@ func NewInterpreter(wd string, out io.Writer) *Interpreter {
 		Prefill("clone", CloneBuiltin{}),
 		Prefill("panic", PanicBuiltin{}),
 		Prefill("assert", AssertBuiltin{}),
+		Prefill("test", TestBuiltin{}),
+		Prefill("validate", ValidateBuiltin{}),
+		Prefill("json", NewJSONObject()),
 	)
 	global := NewEnvironment(Parent(runtime))
 	return &Interpreter{
@@ -131,6 +135,50 @@ type Interpreter struct {
 	global  *Environment
 	scope   *Environment
 	out     io.Writer
+	results []TestResult
+	// testing is the number of test blocks currently being executed, assertions made within a test block are reported
+	// through the result of the test block rather than on their own.
+	testing int
+}
+
+// TestResult is the recorded outcome of a named check made by a script, such as an assertion.
+type TestResult struct {
+	Name    string
+	Passed  bool
+	Message string
+	// Duration is the time taken by a test block, and zero for assertions made outside of test blocks since those are
+	// not timed.
+	Duration time.Duration
+}
+
+// DrainResults returns all test results recorded since the previous call to DrainResults, in the order they were
+// recorded, and clears them from the interpreter.
+func (in *Interpreter) DrainResults() []TestResult {
+	results := in.results
+	in.results = nil
+	return results
+}
+
+func (in *Interpreter) record(result TestResult) {
+	in.results = append(in.results, result)
+}
+
+// test calls fn within a test block. Any error returned by fn, as well as any panic raised while calling it, is
+// returned as an error so that the caller can record it without aborting the script.
+func (in *Interpreter) test(fn Callable) (err error) {
+	in.testing++
+	defer func() {
+		in.testing--
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%w: %v", ErrRuntimeFault, r)
+			}
+		}
+	}()
+	_, err = fn.Call(in)
+	return err
 }
 
 func (in *Interpreter) Execute(program []ast.StatementNode) error {
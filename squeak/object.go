@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"time"
 )
 
 // Object is a broad interface for any data that a Squeak script can process. It does not provide any interface beyond
@@ -37,6 +38,69 @@ func NewRequestObject(req *http.Request) *ObjectInstance {
 	return obj
 }
 
+// Store is a mutable key-value store of textual values that outlives a single script execution. It allows scripts to
+// hand data over to the host application, for example to have values interpolated into later transactions.
+type Store interface {
+	Get(string) (string, bool)
+	Set(string, string)
+	Delete(string)
+	Clear()
+}
+
+// NewSessionObject returns an object exposing the get, set, delete and clear methods of the supplied [squeak.Store] to
+// Squeak scripts. Only strings may be stored, getting a key that has not been set evaluates to nil.
+func NewSessionObject(store Store) *ObjectInstance {
+	obj := &ObjectInstance{Properties: make(map[string]Object)}
+	obj.Properties["get"] = BuiltinMethod{
+		arity: 1,
+		fn: func(_ Object, _ *Interpreter, args ...Object) (Object, error) {
+			k, ok := args[0].(String)
+			if !ok {
+				return nil, fmt.Errorf("%w: session key must be a string", ErrIllegalArgument)
+			}
+			v, ok := store.Get(k.value)
+			if !ok {
+				return nil, nil
+			}
+			return String{v}, nil
+		},
+	}
+	obj.Properties["set"] = BuiltinMethod{
+		arity: 2,
+		fn: func(_ Object, _ *Interpreter, args ...Object) (Object, error) {
+			k, ok := args[0].(String)
+			if !ok {
+				return nil, fmt.Errorf("%w: session key must be a string", ErrIllegalArgument)
+			}
+			v, ok := args[1].(String)
+			if !ok {
+				return nil, fmt.Errorf("%w: session value must be a string", ErrIllegalArgument)
+			}
+			store.Set(k.value, v.value)
+			return v, nil
+		},
+	}
+	obj.Properties["delete"] = BuiltinMethod{
+		arity: 1,
+		fn: func(_ Object, _ *Interpreter, args ...Object) (Object, error) {
+			k, ok := args[0].(String)
+			if !ok {
+				return nil, fmt.Errorf("%w: session key must be a string", ErrIllegalArgument)
+			}
+			store.Delete(k.value)
+			return nil, nil
+		},
+	}
+	obj.Properties["clear"] = BuiltinMethod{
+		arity: 0,
+		fn: func(_ Object, _ *Interpreter, _ ...Object) (Object, error) {
+			store.Clear()
+			return nil, nil
+		},
+	}
+	return obj
+}
+
 type BoundBuiltinMethod struct {
 	this Object
 	impl BuiltinMethod
@@ -88,6 +152,7 @@ func NewResponseObject(res *http.Response, body []byte) *ObjectInstance {
 	obj := &ObjectInstance{Properties: make(map[string]Object)}
 	obj.Properties["status_code"] = Number{float64(res.StatusCode)}
 	obj.Properties["status"] = String{res.Status}
+	obj.Properties["proto"] = String{res.Proto}
 	headers := &ObjectInstance{Properties: make(map[string]Object)}
 	for k, v := range res.Header {
 		headers.Put(k, String{strings.Join(v, ", ")})
@@ -123,6 +188,50 @@ func NewResponseObject(res *http.Response, body []byte) *ObjectInstance {
 	return obj
 }
 
+// NewJSONObject returns an object exposing the stringify and parse methods, which convert between Squeak values and
+// their JSON encoding.
+func NewJSONObject() *ObjectInstance {
+	obj := &ObjectInstance{Properties: make(map[string]Object)}
+	obj.Properties["stringify"] = BuiltinMethod{
+		arity: 1,
+		fn: func(_ Object, _ *Interpreter, args ...Object) (Object, error) {
+			v, err := native(args[0])
+			if err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrIllegalArgument, err)
+			}
+			return String{string(data)}, nil
+		},
+	}
+	obj.Properties["parse"] = BuiltinMethod{
+		arity: 1,
+		fn: func(_ Object, _ *Interpreter, args ...Object) (Object, error) {
+			s, ok := args[0].(String)
+			if !ok {
+				return nil, fmt.Errorf("%w: only strings can be parsed as JSON", ErrIllegalArgument)
+			}
+			var builder Builder
+			if err := json.Unmarshal([]byte(s.value), &builder); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrIllegalArgument, err)
+			}
+			return builder.Object(), nil
+		},
+	}
+	return obj
+}
+
+// NewTimingsObject returns an object holding the supplied durations as numbers of milliseconds.
+func NewTimingsObject(timings map[string]time.Duration) *ObjectInstance {
+	obj := &ObjectInstance{Properties: make(map[string]Object)}
+	for k, v := range timings {
+		obj.Properties[k] = Number{float64(v) / float64(time.Millisecond)}
+	}
+	return obj
+}
+
 type Builder struct {
 	obj Object
 }
@@ -219,6 +328,20 @@ func (b *Builder) asObject(raw any) (Object, error) {
 		return Number{float64(v)}, nil
 	case float64:
 		return Number{v}, nil
+	case bool:
+		return Boolean{v}, nil
+	case nil:
+		return nil, nil
+	case []any:
+		items := make([]Object, 0, len(v))
+		for _, item := range v {
+			obj, err := b.asObject(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, obj)
+		}
+		return &List{slice: items}, nil
 	case map[string]any:
 		props := make(map[string]Object)
 		for k, v := range v {
@@ -234,6 +357,64 @@ func (b *Builder) asObject(raw any) (Object, error) {
 	}
 }
 
+// native converts obj into the native Go form produced by [json.Unmarshal], which is what values are exchanged with the
+// Go standard library as. Callables and methods have no native form and are left out of objects, which for instance
+// strips the builtin methods of a response object.
+func native(obj Object) (any, error) {
+	switch v := obj.(type) {
+	case nil:
+		return nil, nil
+	case String:
+		return v.value, nil
+	case Number:
+		return v.value, nil
+	case Boolean:
+		return v.value, nil
+	case *List:
+		items := make([]any, 0, len(v.slice))
+		for _, item := range v.slice {
+			n, err := native(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, n)
+		}
+		return items, nil
+	case *ObjectInstance:
+		props := make(map[string]any, len(v.Properties))
+		for k, prop := range v.Properties {
+			switch prop.(type) {
+			case Callable, Method:
+				continue
+			}
+			n, err := native(prop)
+			if err != nil {
+				return nil, err
+			}
+			props[k] = n
+		}
+		return props, nil
+	default:
+		return nil, fmt.Errorf("%w: %T has no native representation", ErrIllegalArgument, obj)
+	}
+}
+
+// str returns the textual representation of obj, which may be nil as converted JSON values can hold nil.
+func str(obj Object) string {
+	if obj == nil {
+		return "nil"
+	}
+	return obj.String()
+}
+
+// cloneObject returns a clone of obj, which may be nil as converted JSON values can hold nil.
+func cloneObject(obj Object) Object {
+	if obj == nil {
+		return nil
+	}
+	return obj.Clone()
+}
+
 // ObjectInstance is an asObject instance, which consists of a collection of named data as well as behaviours coupled to the
 // data.
 type ObjectInstance struct {
@@ -244,7 +425,7 @@ func (i *ObjectInstance) String() string {
 	sb := strings.Builder{}
 	sb.WriteString("Object {")
 	for k, v := range i.Properties {
-		sb.WriteString(fmt.Sprintf("%s: %s", k, v.String()))
+		sb.WriteString(fmt.Sprintf("%s: %s", k, str(v)))
 	}
 	sb.WriteString("}")
 	return sb.String()
@@ -253,7 +434,7 @@ func (i *ObjectInstance) String() string {
 func (i *ObjectInstance) Clone() Object {
 	props := make(map[string]Object)
 	for k, v := range i.Properties {
-		props[k] = v.Clone()
+		props[k] = cloneObject(v)
 	}
 	return &ObjectInstance{Properties: props}
 }
@@ -472,7 +653,7 @@ type List struct {
 func (l *List) String() string {
 	items := make([]string, len(l.slice))
 	for i := range l.slice {
-		items[i] = l.slice[i].String()
+		items[i] = str(l.slice[i])
 	}
 	return fmt.Sprintf("[%s]", strings.Join(items, ","))
 }
@@ -480,7 +661,7 @@ func (l *List) String() string {
 func (l *List) Clone() Object {
 	clone := make([]Object, len(l.slice))
 	for i, v := range l.slice {
-		clone[i] = v.Clone()
+		clone[i] = cloneObject(v)
 	}
 	return &List{slice: clone}
 }
@@ -1,6 +1,8 @@
 package squeak
 
 import (
+	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -77,3 +79,101 @@ func TestBuilder_UnmarshalXML(t *testing.T) {
 		},
 	}, builder.Object())
 }
+
+type mapStore map[string]string
+
+func (m mapStore) Get(k string) (string, bool) {
+	v, ok := m[k]
+	return v, ok
+}
+
+func (m mapStore) Set(k, v string) {
+	m[k] = v
+}
+
+func (m mapStore) Delete(k string) {
+	delete(m, k)
+}
+
+func (m mapStore) Clear() {
+	clear(m)
+}
+
+func TestNewSessionObject(t *testing.T) {
+	src := `
+	session.set("id_token", "abc");
+	println(session.get("id_token"));
+	session.set("refresh_token", "def");
+	session.delete("refresh_token");
+	if session.get("refresh_token") == nil {
+		println("deleted");
+	}
+	`
+	program, err := ParseString(src)
+	assert.Nil(t, err)
+	out := bytes.NewBufferString("")
+	store := mapStore{}
+	in := NewInterpreter("", out)
+	in.Declare("session", NewSessionObject(store))
+	assert.Nil(t, in.Execute(program))
+	assert.Equal(t, "abc\ndeleted\n", out.String())
+	assert.Equal(t, mapStore{"id_token": "abc"}, store)
+
+	program, err = ParseString(`session.clear();`)
+	assert.Nil(t, err)
+	assert.Nil(t, in.Execute(program))
+	assert.Empty(t, store)
+
+	program, err = ParseString(`session.set("id_token", 1);`)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, in.Execute(program), ErrIllegalArgument)
+}
+
+func TestBuilder_UnmarshalJSON(t *testing.T) {
+	data := `{"users": [{"name": "crookdc", "admin": true, "age": 27, "partner": null}], "next": null}`
+	builder := Builder{}
+	err := json.Unmarshal([]byte(data), &builder)
+	assert.Nil(t, err)
+	assert.Equal(t, &ObjectInstance{
+		Properties: map[string]Object{
+			"users": &List{
+				slice: []Object{
+					&ObjectInstance{
+						Properties: map[string]Object{
+							"name":    String{"crookdc"},
+							"admin":   Boolean{true},
+							"age":     Number{27},
+							"partner": nil,
+						},
+					},
+				},
+			},
+			"next": nil,
+		},
+	}, builder.Object())
+
+	err = json.Unmarshal([]byte(`[1, "two", false, null]`), &builder)
+	assert.Nil(t, err)
+	assert.Equal(t, &List{slice: []Object{Number{1}, String{"two"}, Boolean{false}, nil}}, builder.Object())
+}
+
+func TestNewJSONObject(t *testing.T) {
+	src := `
+	var user = json.parse(payload);
+	println(user.roles[0]);
+	user.roles.add("user");
+	println(json.stringify(user));
+	println(json.stringify([1, "two", nil]));
+	`
+	program, err := ParseString(src)
+	assert.Nil(t, err)
+	out := bytes.NewBufferString("")
+	in := NewInterpreter("", out)
+	in.Declare("payload", String{`{"name": "crookdc", "roles": ["admin"], "active": true}`})
+	assert.Nil(t, in.Execute(program))
+	assert.Equal(t, "admin\n{\"active\":true,\"name\":\"crookdc\",\"roles\":[\"admin\",\"user\"]}\n[1,\"two\",null]\n", out.String())
+
+	program, err = ParseString(`json.parse("{");`)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, in.Execute(program), ErrIllegalArgument)
+}
@@ -0,0 +1,393 @@
+package squeak
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Violation is a single way in which a value fails to conform to a JSON Schema. Path is a JSON pointer to the offending
+// part of the value, which is empty when the value as a whole is at fault.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+// Validate checks value against a JSON Schema, both given in their native Go form as produced by [json.Unmarshal]. A
+// subset of draft 2020-12 is supported: type, enum, const, required, properties, additionalProperties, items, pattern,
+// minLength, maxLength, minimum, maximum, exclusiveMinimum, exclusiveMaximum, minItems, maxItems, allOf, anyOf, oneOf
+// and $ref within the schema itself. Keywords outside the subset are ignored, while malformed keywords result in an
+// error, as do references that end up referring to themselves without descending into the value.
+func Validate(schema, value any) ([]Violation, error) {
+	v := validator{root: schema, active: make(map[reference]bool)}
+	if err := v.validate(schema, value, ""); err != nil {
+		return nil, err
+	}
+	return v.violations, nil
+}
+
+type validator struct {
+	root any
+	// active holds the references being applied, which would recur endlessly if applied again to the same value.
+	active     map[reference]bool
+	violations []Violation
+}
+
+// reference is a $ref applied to the value at path.
+type reference struct {
+	ref  string
+	path string
+}
+
+func (v *validator) violate(path, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(schema, value any, path string) error {
+	var s map[string]any
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			v.violate(path, "no value is allowed")
+		}
+		return nil
+	case map[string]any:
+		s = schema
+	default:
+		return fmt.Errorf("%w: schema at %q must be an object or a boolean", ErrIllegalArgument, path)
+	}
+	if ref, ok := s["$ref"]; ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			return err
+		}
+		r := reference{ref: fmt.Sprint(ref), path: path}
+		if v.active[r] {
+			return fmt.Errorf("%w: cyclic reference %s at %q", ErrIllegalArgument, r.ref, path)
+		}
+		v.active[r] = true
+		err = v.validate(target, value, path)
+		delete(v.active, r)
+		if err != nil {
+			return err
+		}
+	}
+	if t, ok := s["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []any:
+			for _, alt := range t {
+				name, ok := alt.(string)
+				if !ok {
+					return fmt.Errorf("%w: malformed type at %q", ErrIllegalArgument, path)
+				}
+				types = append(types, name)
+			}
+		default:
+			return fmt.Errorf("%w: malformed type at %q", ErrIllegalArgument, path)
+		}
+		if !slices.ContainsFunc(types, func(name string) bool { return is(name, value) }) {
+			v.violate(path, "expected %s but got %s", strings.Join(types, " or "), typeOf(value))
+			// The remaining keywords make little sense for a value of the wrong type.
+			return nil
+		}
+	}
+	if enum, ok := s["enum"]; ok {
+		values, ok := enum.([]any)
+		if !ok {
+			return fmt.Errorf("%w: enum at %q must be an array", ErrIllegalArgument, path)
+		}
+		if !slices.ContainsFunc(values, func(allowed any) bool { return reflect.DeepEqual(allowed, value) }) {
+			v.violate(path, "value is not one of the allowed values")
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.violate(path, "value must be %s", encode(c))
+	}
+	if err := v.composition(s, value, path); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		return v.object(s, value, path)
+	case []any:
+		return v.array(s, value, path)
+	case string:
+		return v.string(s, value, path)
+	case float64:
+		return v.number(s, value, path)
+	}
+	return nil
+}
+
+func (v *validator) composition(s map[string]any, value any, path string) error {
+	// Subschemas of anyOf and oneOf are validated in isolation since only the number of matches is of interest.
+	matches := func(keyword string) (int, error) {
+		schemas, ok := s[keyword].([]any)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s at %q must be an array", ErrIllegalArgument, keyword, path)
+		}
+		n := 0
+		for _, sub := range schemas {
+			isolated := validator{root: v.root, active: v.active}
+			if err := isolated.validate(sub, value, path); err != nil {
+				return 0, err
+			}
+			if len(isolated.violations) == 0 {
+				n++
+			}
+		}
+		return n, nil
+	}
+	if all, ok := s["allOf"]; ok {
+		schemas, ok := all.([]any)
+		if !ok {
+			return fmt.Errorf("%w: allOf at %q must be an array", ErrIllegalArgument, path)
+		}
+		for _, sub := range schemas {
+			if err := v.validate(sub, value, path); err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := s["anyOf"]; ok {
+		n, err := matches("anyOf")
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			v.violate(path, "value does not match any of the schemas")
+		}
+	}
+	if _, ok := s["oneOf"]; ok {
+		n, err := matches("oneOf")
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			v.violate(path, "value matches %d schemas but must match exactly one", n)
+		}
+	}
+	return nil
+}
+
+func (v *validator) object(s map[string]any, value map[string]any, path string) error {
+	if required, ok := s["required"]; ok {
+		names, ok := required.([]any)
+		if !ok {
+			return fmt.Errorf("%w: required at %q must be an array", ErrIllegalArgument, path)
+		}
+		for _, name := range names {
+			name, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("%w: required at %q must hold strings", ErrIllegalArgument, path)
+			}
+			if _, ok := value[name]; !ok {
+				v.violate(path, "missing required property %q", name)
+			}
+		}
+	}
+	props, _ := s["properties"].(map[string]any)
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	// Properties are visited in lexical order to make the order of the violations predictable.
+	slices.Sort(keys)
+	for _, k := range keys {
+		child := path + "/" + escape(k)
+		if sub, ok := props[k]; ok {
+			if err := v.validate(sub, value[k], child); err != nil {
+				return err
+			}
+			continue
+		}
+		additional, ok := s["additionalProperties"]
+		if !ok {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			v.violate(path, "additional property %q is not allowed", k)
+			continue
+		}
+		if err := v.validate(additional, value[k], child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *validator) array(s map[string]any, value []any, path string) error {
+	if n, ok, err := limit(s, "minItems", path); err != nil {
+		return err
+	} else if ok && float64(len(value)) < n {
+		v.violate(path, "array must hold at least %s items", number(n))
+	}
+	if n, ok, err := limit(s, "maxItems", path); err != nil {
+		return err
+	} else if ok && float64(len(value)) > n {
+		v.violate(path, "array must hold at most %s items", number(n))
+	}
+	items, ok := s["items"]
+	if !ok {
+		return nil
+	}
+	for i, item := range value {
+		if err := v.validate(items, item, path+"/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *validator) string(s map[string]any, value string, path string) error {
+	length := float64(len([]rune(value)))
+	if n, ok, err := limit(s, "minLength", path); err != nil {
+		return err
+	} else if ok && length < n {
+		v.violate(path, "string must be at least %s characters long", number(n))
+	}
+	if n, ok, err := limit(s, "maxLength", path); err != nil {
+		return err
+	} else if ok && length > n {
+		v.violate(path, "string must be at most %s characters long", number(n))
+	}
+	if p, ok := s["pattern"]; ok {
+		expr, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%w: pattern at %q must be a string", ErrIllegalArgument, path)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("%w: pattern at %q: %w", ErrIllegalArgument, path, err)
+		}
+		if !re.MatchString(value) {
+			v.violate(path, "string does not match pattern %s", expr)
+		}
+	}
+	return nil
+}
+
+func (v *validator) number(s map[string]any, value float64, path string) error {
+	checks := []struct {
+		keyword string
+		fails   func(n float64) bool
+		message string
+	}{
+		{"minimum", func(n float64) bool { return value < n }, "value must be at least %s"},
+		{"maximum", func(n float64) bool { return value > n }, "value must be at most %s"},
+		{"exclusiveMinimum", func(n float64) bool { return value <= n }, "value must be greater than %s"},
+		{"exclusiveMaximum", func(n float64) bool { return value >= n }, "value must be less than %s"},
+	}
+	for _, check := range checks {
+		n, ok, err := limit(s, check.keyword, path)
+		if err != nil {
+			return err
+		}
+		if ok && check.fails(n) {
+			v.violate(path, check.message, number(n))
+		}
+	}
+	return nil
+}
+
+// resolve returns the subschema referenced by ref, which must be a JSON pointer into the root schema.
+func (v *validator) resolve(ref any) (any, error) {
+	s, ok := ref.(string)
+	if !ok || !strings.HasPrefix(s, "#") {
+		return nil, fmt.Errorf("%w: only references within the schema are supported: %v", ErrIllegalArgument, ref)
+	}
+	target := v.root
+	if s == "#" {
+		return target, nil
+	}
+	for _, segment := range strings.Split(strings.TrimPrefix(s, "#/"), "/") {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		switch t := target.(type) {
+		case map[string]any:
+			target, ok = t[segment]
+		case []any:
+			i, err := strconv.Atoi(segment)
+			ok = err == nil && i >= 0 && i < len(t)
+			if ok {
+				target = t[i]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: unresolved reference %s", ErrIllegalArgument, s)
+		}
+	}
+	return target, nil
+}
+
+// limit returns the numerical value of keyword in s, if present.
+func limit(s map[string]any, keyword, path string) (float64, bool, error) {
+	raw, ok := s[keyword]
+	if !ok {
+		return 0, false, nil
+	}
+	n, ok := raw.(float64)
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %s at %q must be a number", ErrIllegalArgument, keyword, path)
+	}
+	return n, true, nil
+}
+
+// is reports whether value is of the named JSON Schema type.
+func is(name string, value any) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	default:
+		return typeOf(value) == name || name == "number" && typeOf(value) == "integer"
+	}
+}
+
+// typeOf returns the name of the JSON Schema type of value, where integral numbers are considered integers.
+func typeOf(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func encode(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func number(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func escape(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1")
+}
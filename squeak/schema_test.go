@@ -0,0 +1,119 @@
+package squeak
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["id", "name", "roles"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"name": {"type": "string", "minLength": 2, "pattern": "^[A-Z]"},
+			"email": {"type": ["string", "null"]},
+			"roles": {"type": "array", "maxItems": 2, "items": {"$ref": "#/$defs/role"}},
+			"score": {"type": "number", "exclusiveMaximum": 10}
+		},
+		"$defs": {
+			"role": {"enum": ["admin", "user"]}
+		}
+	}`
+	tests := []struct {
+		name     string
+		value    string
+		expected []Violation
+	}{
+		{
+			name:  "valid",
+			value: `{"id": 1, "name": "Jane", "email": null, "roles": ["admin"], "score": 9.5}`,
+		},
+		{
+			name:  "wrong type",
+			value: `[]`,
+			expected: []Violation{
+				{Path: "", Message: "expected object but got array"},
+			},
+		},
+		{
+			name:  "missing and additional properties",
+			value: `{"id": 1, "nickname": "jd"}`,
+			expected: []Violation{
+				{Path: "", Message: `missing required property "name"`},
+				{Path: "", Message: `missing required property "roles"`},
+				{Path: "", Message: `additional property "nickname" is not allowed`},
+			},
+		},
+		{
+			name:  "nested violations",
+			value: `{"id": 0.5, "name": "j", "roles": ["admin", "guest", "user"], "score": 10}`,
+			expected: []Violation{
+				{Path: "/id", Message: "expected integer but got number"},
+				{Path: "/name", Message: "string must be at least 2 characters long"},
+				{Path: "/name", Message: "string does not match pattern ^[A-Z]"},
+				{Path: "/roles", Message: "array must hold at most 2 items"},
+				{Path: "/roles/1", Message: "value is not one of the allowed values"},
+				{Path: "/score", Message: "value must be less than 10"},
+			},
+		},
+	}
+	var s any
+	assert.Nil(t, json.Unmarshal([]byte(schema), &s))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value any
+			assert.Nil(t, json.Unmarshal([]byte(test.value), &value))
+			violations, err := Validate(s, value)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, violations)
+		})
+	}
+}
+
+func TestValidate_Composition(t *testing.T) {
+	var s any
+	assert.Nil(t, json.Unmarshal([]byte(`{"oneOf": [{"type": "integer"}, {"type": "number", "minimum": 5}]}`), &s))
+	violations, err := Validate(s, 1.5)
+	assert.Nil(t, err)
+	assert.Equal(t, []Violation{{Path: "", Message: "value matches 0 schemas but must match exactly one"}}, violations)
+	violations, err = Validate(s, 6.0)
+	assert.Nil(t, err)
+	assert.Equal(t, []Violation{{Path: "", Message: "value matches 2 schemas but must match exactly one"}}, violations)
+	violations, err = Validate(s, 1.0)
+	assert.Nil(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestValidate_MalformedSchema(t *testing.T) {
+	_, err := Validate(map[string]any{"pattern": "("}, "value")
+	assert.ErrorIs(t, err, ErrIllegalArgument)
+	_, err = Validate(map[string]any{"$ref": "other.json#/defs/a"}, "value")
+	assert.ErrorIs(t, err, ErrIllegalArgument)
+}
+
+func TestValidate_CyclicReference(t *testing.T) {
+	schemas := []string{
+		`{"$ref": "#"}`,
+		`{"$defs": {"a": {"$ref": "#/$defs/b"}, "b": {"allOf": [{"$ref": "#/$defs/a"}]}}, "$ref": "#/$defs/a"}`,
+		`{"anyOf": [{"$ref": "#"}]}`,
+	}
+	for _, src := range schemas {
+		var s any
+		assert.Nil(t, json.Unmarshal([]byte(src), &s))
+		_, err := Validate(s, "value")
+		assert.ErrorIs(t, err, ErrIllegalArgument, src)
+	}
+
+	// A schema may refer to itself as long as every reference descends into the value.
+	var tree any
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {"children": {"type": "array", "items": {"$ref": "#"}}}
+	}`), &tree))
+	violations, err := Validate(tree, map[string]any{"children": []any{map[string]any{"children": []any{1.0}}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []Violation{{Path: "/children/0/children/0", Message: "expected object but got integer"}}, violations)
+}
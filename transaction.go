@@ -1,23 +1,30 @@
 package pia
 
 import (
-	"bytes"
+	"errors"
 	"github.com/ernilsson/pia/squeak"
 	"gopkg.in/yaml.v3"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type input struct {
-	File   string `yaml:"file"`
-	Inline string `yaml:"inline"`
+	File   string `yaml:"file,omitempty"`
+	Inline string `yaml:"inline,omitempty"`
+	// Interpolate opts a file out of interpolation when set to false. Inline input is always interpolated along with
+	// the rest of the transaction.
+	Interpolate *bool `yaml:"interpolate,omitempty"`
 }
 
-func (in *input) reader(wd string) (io.Reader, error) {
+// reader returns the input, with a file interpolated using resolver unless the resolver is nil or the file has been
+// opted out of interpolation.
+func (in *input) reader(wd string, resolver KeyResolver) (io.Reader, error) {
 	if in.Inline != "" {
 		return strings.NewReader(in.Inline), nil
 	}
@@ -26,50 +33,145 @@ func (in *input) reader(wd string) (io.Reader, error) {
 		if !filepath.IsAbs(path) {
 			path = filepath.Join(wd, path)
 		}
-		return os.OpenFile(path, os.O_RDONLY, os.ModeAppend)
+		f, err := os.OpenFile(path, os.O_RDONLY, os.ModeAppend)
+		if err != nil {
+			return nil, err
+		}
+		if resolver == nil || !in.interpolated() {
+			return f, nil
+		}
+		return WrapReader(resolver, f), nil
 	}
 	return nil, nil
 }
 
+// interpolated reports whether a file is to be interpolated, which it is unless opted out of.
+func (in *input) interpolated() bool {
+	return in.Interpolate == nil || *in.Interpolate
+}
+
 type body struct {
-	input `yaml:",inline"`
-	Form  map[string]string `yaml:"form"`
+	input     `yaml:",inline"`
+	Form      map[string]string `yaml:"form,omitempty"`
+	Multipart []part            `yaml:"multipart,omitempty"`
+	JSON      yaml.Node         `yaml:"json,omitempty"`
+	// YAML is an alias of JSON for bodies that are written as YAML but sent as JSON all the same.
+	YAML yaml.Node `yaml:"yaml,omitempty"`
+	// resolved reports whether the file of a verbatim body has been interpolated while being read.
+	resolved bool
+}
+
+// IsZero reports whether no body is configured, which allows the body to be omitted when a transaction is marshalled.
+func (b body) IsZero() bool {
+	return b.input == (input{}) && len(b.Form) == 0 && len(b.Multipart) == 0 && b.tree() == nil
 }
 
-func (b *body) reader(wd string) (io.Reader, error) {
+// tree returns the YAML tree of a body given as either JSON or YAML, or nil if the body is given in another way.
+func (b *body) tree() *yaml.Node {
+	switch {
+	case b.JSON.Kind != 0:
+		return &b.JSON
+	case b.YAML.Kind != 0:
+		return &b.YAML
+	}
+	return nil
+}
+
+// reader returns the encoded body along with the content type implied by its encoding. The content type is empty when
+// the body is given verbatim, in which case it is up to the user to declare it.
+func (b *body) reader(wd string, resolver KeyResolver) (io.Reader, string, error) {
+	if tree := b.tree(); tree != nil {
+		r, err := jsonBody(tree)
+		return r, "application/json", err
+	}
+	if len(b.Multipart) > 0 {
+		return multipartBody(wd, b.Multipart)
+	}
 	if len(b.Form) == 0 {
-		return b.input.reader(wd)
+		r, err := b.input.reader(wd, resolver)
+		b.resolved = b.File != "" && b.Inline == "" && resolver != nil && b.interpolated()
+		return r, "", err
 	}
 	body := url.Values{}
 	for k, v := range b.Form {
 		body.Set(k, v)
 	}
-	return strings.NewReader(body.Encode()), nil
+	return strings.NewReader(body.Encode()), "", nil
 }
 
 // transaction represents a Transaction value in its textual YAML state. This data structure serves as a simple midway
 // stop while parsing text data into a Transaction.
 type transaction struct {
-	URL struct {
+	Extends string `yaml:"extends,omitempty"`
+	Method  string `yaml:"method"`
+	URL     struct {
 		Target string            `yaml:"target"`
-		Query  map[string]string `yaml:"query"`
+		Query  map[string]string `yaml:"query,omitempty"`
 	} `yaml:"url"`
-	Method  string            `yaml:"method"`
-	Headers map[string]string `yaml:"headers"`
-	Body    body              `yaml:"body"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    body              `yaml:"body,omitempty"`
 	Hooks   struct {
-		Before input `yaml:"before"`
-		After  input `yaml:"after"`
-	} `yaml:"hooks"`
+		Before input `yaml:"before,omitempty"`
+		After  input `yaml:"after,omitempty"`
+	} `yaml:"hooks,omitempty"`
+	Client *client `yaml:"client,omitempty"`
+
+	// before and after are the hooks of the transaction along with those it inherits, in the order they are run.
+	before, after []input
+}
+
+// encodeYAML writes v to w as YAML, indented the way transaction files are written by hand.
+func encodeYAML(w io.Writer, v any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ParseOption configures how [pia.ParseTransaction] parses a transaction.
+type ParseOption func(opts *parseOptions)
+
+type parseOptions struct {
+	resolver KeyResolver
+	root     string
+}
+
+// WithResolver interpolates the transaction using resolver, along with the files it refers to for its body and hooks
+// except for those opted out of interpolation using "interpolate: false". The transaction is expected in its raw form,
+// since it is interpolated once it has been decoded. That way values are never parsed as YAML, which means that they
+// cannot break the document, and that values within JSON and YAML bodies are always serialized as JSON strings.
+func WithResolver(resolver KeyResolver) ParseOption {
+	return func(opts *parseOptions) {
+		opts.resolver = resolver
+	}
 }
 
-// ParseTransaction reads the provided transaction configuration and builds a Transaction value from it.
-func ParseTransaction(wd string, r io.Reader) (*Transaction, error) {
-	var cfg transaction
-	err := yaml.NewDecoder(r).Decode(&cfg)
+// WithRoot looks for defaults files in the parent directories of the transaction up to and including root, rather than
+// only in the directory of the transaction. It has no effect for transactions outside root.
+func WithRoot(root string) ParseOption {
+	return func(opts *parseOptions) {
+		opts.root = root
+	}
+}
+
+// ParseTransaction reads the provided transaction configuration and builds a Transaction value from it. The
+// configuration inherits from the files named by its "extends" key, and from any [pia.DefaultsFile] in wd, or in the
+// directories between wd and the root given using [pia.WithRoot]. The transaction and the files it inherits from are
+// interpolated using the resolver given using [pia.WithResolver], if any.
+func ParseTransaction(wd string, r io.Reader, opts ...ParseOption) (*Transaction, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	cfg, err := options.decodeReader(r)
 	if err != nil {
 		return nil, err
 	}
+	if err := inherit(&cfg, wd, options.root, options.decode); err != nil {
+		return nil, err
+	}
 	tx := Transaction{
 		WD: wd,
 		URL: struct {
@@ -81,23 +183,93 @@ func ParseTransaction(wd string, r io.Reader) (*Transaction, error) {
 		},
 		Method:  cfg.Method,
 		Headers: cfg.Headers,
+		body:    &cfg.Body,
 	}
 
-	tx.Body, err = cfg.Body.reader(wd)
+	var ct string
+	tx.Body, ct, err = cfg.Body.reader(wd, options.resolver)
 	if err != nil {
 		return nil, err
 	}
-	tx.Hooks.Before, err = cfg.Hooks.Before.reader(wd)
+	switch name, declared := tx.header("Content-Type"); {
+	case ct == "":
+	case name == "":
+		if tx.Headers == nil {
+			tx.Headers = make(map[string]string)
+		}
+		tx.Headers["Content-Type"] = ct
+	case strings.HasPrefix(ct, "multipart/"):
+		tx.Headers[name] = withBoundary(declared, ct)
+	}
+	tx.Hooks.Before, err = hook(cfg.before, wd, options.resolver)
 	if err != nil {
 		return nil, err
 	}
-	tx.Hooks.After, err = cfg.Hooks.After.reader(wd)
+	tx.Hooks.After, err = hook(cfg.after, wd, options.resolver)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Client != nil {
+		tx.Client, err = cfg.Client.build(wd)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &tx, nil
 }
 
+// decodeReader decodes the transaction read from r, interpolated using the resolver of the options if there is one.
+func (opts *parseOptions) decodeReader(r io.Reader) (transaction, error) {
+	if opts.resolver == nil {
+		var cfg transaction
+		err := yaml.NewDecoder(r).Decode(&cfg)
+		return cfg, err
+	}
+	return decodeTransaction(r, func(p placeholder) (string, error) {
+		return opts.resolver.Resolve(p.Key)
+	})
+}
+
+// decode reads the transaction file at path, interpolated using the resolver of the options if there is one. Empty
+// files are decoded as empty transactions.
+func (opts *parseOptions) decode(path string) (transaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return transaction{}, err
+	}
+	defer f.Close()
+	cfg, err := opts.decodeReader(f)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// hook returns a single script running each of the supplied hooks in order, or nil if there are none.
+func hook(hooks []input, wd string, resolver KeyResolver) (io.Reader, error) {
+	var readers []io.Reader
+	for _, h := range hooks {
+		r, err := h.reader(wd, resolver)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+		if len(readers) > 0 {
+			readers = append(readers, strings.NewReader("\n"))
+		}
+		readers = append(readers, r)
+	}
+	switch len(readers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return readers[0], nil
+	}
+	return io.MultiReader(readers...), nil
+}
+
 type Transaction struct {
 	WD  string
 	URL struct {
@@ -111,9 +283,16 @@ type Transaction struct {
 		Before io.Reader
 		After  io.Reader
 	}
+	// Client is used to send the request, if nil then [http.DefaultClient] is used.
+	Client *http.Client
+
+	// body is the configuration that Body was built from, which is only available for a parsed Transaction.
+	body *body
 }
 
-func (tx *Transaction) Execute(in *squeak.Interpreter) (*http.Response, error) {
+// Execute sends the request described by the Transaction and runs its hooks using the supplied interpreter. If the
+// after hook fails then the response is returned together with the error so that callers are still able to present it.
+func (tx *Transaction) Execute(in *squeak.Interpreter) (*Response, error) {
 	req, err := tx.Request()
 	if err != nil {
 		return nil, err
@@ -123,13 +302,24 @@ func (tx *Transaction) Execute(in *squeak.Interpreter) (*http.Response, error) {
 			return nil, err
 		}
 	}
-	res, err := http.DefaultClient.Do(req)
+	client := tx.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	tr := &tracer{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tr.trace()))
+	tr.start = time.Now()
+	raw, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tr.response(raw)
 	if err != nil {
 		return nil, err
 	}
 	if tx.Hooks.After != nil {
 		if err := tx.after(in, res); err != nil {
-			return nil, err
+			return res, err
 		}
 	}
 	return res, nil
@@ -147,27 +337,37 @@ func (tx *Transaction) before(in *squeak.Interpreter, req *http.Request) error {
 	return nil
 }
 
-func (tx *Transaction) after(in *squeak.Interpreter, res *http.Response) error {
+func (tx *Transaction) after(in *squeak.Interpreter, res *Response) error {
 	ast, err := squeak.Parse(tx.Hooks.After)
 	if err != nil {
 		return err
 	}
-	var body []byte
-	if res.Body != nil {
-		body, err = io.ReadAll(res.Body)
-		if err != nil {
-			return err
-		}
-		// Allow the response body to be re-read by assigning a new io.Reader to it.
-		res.Body = io.NopCloser(bytes.NewBuffer(body))
-	}
-	in.Declare("response", squeak.NewResponseObject(res, body))
+	obj := squeak.NewResponseObject(res.Response, res.body)
+	obj.Put("timings", squeak.NewTimingsObject(map[string]time.Duration{
+		"dns":        res.Timings.DNS,
+		"connect":    res.Timings.Connect,
+		"tls":        res.Timings.TLS,
+		"first_byte": res.Timings.FirstByte,
+		"total":      res.Timings.Total,
+	}))
+	in.Declare("response", obj)
 	if err := in.Execute(ast); err != nil {
 		return err
 	}
 	return nil
 }
 
+// header returns the name and value of the header k as declared by the Transaction, regardless of the casing used for
+// its name. The name is empty if the header is not declared.
+func (tx *Transaction) header(k string) (string, string) {
+	for h, v := range tx.Headers {
+		if strings.EqualFold(h, k) {
+			return h, v
+		}
+	}
+	return "", ""
+}
+
 // Request returns an [http.Request] which mirrors the configuration represented by the Transaction. The ownership of
 // the request value is given to the caller, this means that the Transaction struct will not keep any reference to the
 // produced request after returning and eventually closing the request is up to the caller.
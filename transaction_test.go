@@ -2,12 +2,19 @@ package pia
 
 import (
 	"fmt"
+	"github.com/ernilsson/pia/squeak"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTransaction_Request(t *testing.T) {
@@ -154,3 +161,366 @@ func TestTransaction_Request(t *testing.T) {
 		})
 	}
 }
+
+func TestTransaction_Execute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	t.Run("failed assertion in after hook returns response", func(t *testing.T) {
+		tx, err := ParseTransaction("", strings.NewReader(fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+hooks:
+  after:
+    inline: assert(response.status_code == 200, "expected 200");
+`, srv.URL)))
+		assert.Nil(t, err)
+		res, err := tx.Execute(squeak.NewInterpreter("", io.Discard))
+		assert.ErrorIs(t, err, squeak.ErrFailedAssertion)
+		assert.NotNil(t, res)
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	})
+
+	t.Run("response metadata", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		}))
+		defer srv.Close()
+		tx, err := ParseTransaction("", strings.NewReader(fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+hooks:
+  after:
+    inline: |
+      assert(response.timings.total >= response.timings.first_byte, "total includes first byte");
+      assert(response.timings.first_byte > 0, "first byte is measured");
+      assert(response.proto == "HTTP/1.1", "protocol is HTTP/1.1");
+`, srv.URL)))
+		assert.Nil(t, err)
+		res, err := tx.Execute(squeak.NewInterpreter("", io.Discard))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), res.Size)
+		assert.Equal(t, srv.Listener.Addr().String(), res.RemoteAddr)
+		assert.Equal(t, "HTTP/1.1", res.Proto)
+		assert.Greater(t, res.Timings.Total, time.Duration(0))
+		assert.GreaterOrEqual(t, res.Timings.Total, res.Timings.FirstByte)
+		body, err := io.ReadAll(res.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, "hello", string(body))
+	})
+
+	t.Run("failed test block in after hook does not fail execution", func(t *testing.T) {
+		tx, err := ParseTransaction("", strings.NewReader(fmt.Sprintf(`
+method: GET
+url:
+  target: %s
+hooks:
+  after:
+    inline: |
+      test("status is 200", function() {
+        assert(response.status_code == 200, "expected 200");
+      });
+      test("status is 418", function() {
+        assert(response.status_code == 418, "expected 418");
+      });
+`, srv.URL)))
+		assert.Nil(t, err)
+		in := squeak.NewInterpreter("", io.Discard)
+		res, err := tx.Execute(in)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+		results := in.DrainResults()
+		assert.Len(t, results, 2)
+		assert.False(t, results[0].Passed)
+		assert.True(t, results[1].Passed)
+	})
+}
+
+func TestParseTransaction_Body(t *testing.T) {
+	t.Run("form", func(t *testing.T) {
+		tx, err := ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/login
+headers:
+  Content-Type: application/x-www-form-urlencoded
+body:
+  form:
+    username: admin
+`))
+		assert.Nil(t, err)
+		req, err := tx.Request()
+		assert.Nil(t, err)
+		assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+		assert.Nil(t, req.ParseForm())
+		assert.Equal(t, "admin", req.PostForm.Get("username"))
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		wd := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, "avatar.png"), []byte("not really a png"), 0666))
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, "notes"), []byte("some notes"), 0666))
+		tx, err := ParseTransaction(wd, strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/upload
+body:
+  multipart:
+    - name: description
+      value: my avatar
+    - name: avatar
+      file: avatar.png
+    - name: attachment
+      file: notes
+      filename: notes.txt
+      content_type: text/plain
+`))
+		assert.Nil(t, err)
+		req, err := tx.Request()
+		assert.Nil(t, err)
+		assert.Nil(t, req.ParseMultipartForm(1024))
+		assert.Equal(t, "my avatar", req.MultipartForm.Value["description"][0])
+
+		avatar := req.MultipartForm.File["avatar"][0]
+		assert.Equal(t, "avatar.png", avatar.Filename)
+		assert.Equal(t, "image/png", avatar.Header.Get("Content-Type"))
+		attachment := req.MultipartForm.File["attachment"][0]
+		assert.Equal(t, "notes.txt", attachment.Filename)
+		assert.Equal(t, "text/plain", attachment.Header.Get("Content-Type"))
+		f, err := attachment.Open()
+		assert.Nil(t, err)
+		data, err := io.ReadAll(f)
+		assert.Nil(t, err)
+		assert.Equal(t, "some notes", string(data))
+	})
+
+	t.Run("multipart keeps declared content type with the boundary of the body", func(t *testing.T) {
+		declared := map[string]string{
+			"multipart/mixed; boundary=abc": "multipart/mixed",
+			"multipart/form-data":           "multipart/form-data",
+		}
+		for ct, expected := range declared {
+			tx, err := ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/upload
+headers:
+  content-type: `+ct+`
+body:
+  multipart:
+    - name: description
+      value: my avatar
+`))
+			assert.Nil(t, err)
+			assert.Len(t, tx.Headers, 1)
+			mediatype, params, err := mime.ParseMediaType(tx.Headers["content-type"])
+			assert.Nil(t, err)
+			assert.Equal(t, expected, mediatype)
+			assert.NotEqual(t, "abc", params["boundary"])
+			form, err := multipart.NewReader(tx.Body, params["boundary"]).ReadForm(1024)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"my avatar"}, form.Value["description"])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		tx, err := ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/users
+body:
+  json:
+    name: John "Johnny" Doe
+    age: 42
+    admin: false
+    zip: "12345"
+    manager: null
+    roles:
+      - reader
+      - writer
+    address:
+      city: Malmö
+`))
+		assert.Nil(t, err)
+		req, err := tx.Request()
+		assert.Nil(t, err)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		data, err := io.ReadAll(req.Body)
+		assert.Nil(t, err)
+		assert.Equal(
+			t,
+			`{"name":"John \"Johnny\" Doe","age":42,"admin":false,"zip":"12345","manager":null,"roles":["reader","writer"],"address":{"city":"Malmö"}}`,
+			string(data),
+		)
+	})
+
+	t.Run("json with interpolated value", func(t *testing.T) {
+		tx, err := ParseTransaction("", WrapReader(MapResolver{"name": `John "Johnny" Doe`}, strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/users
+body:
+  json:
+    name: ${name}
+`)))
+		assert.Nil(t, err)
+		req, err := tx.Request()
+		assert.Nil(t, err)
+		data, err := io.ReadAll(req.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"name":"John \"Johnny\" Doe"}`, string(data))
+	})
+
+	t.Run("json interpolated after decoding", func(t *testing.T) {
+		resolver := MapResolver{
+			"colon":  "p@ss: word",
+			"zero":   "01234",
+			"bool":   "true",
+			"hash":   "# hash",
+			"lines":  "first\nsecond",
+			"braces": "{x}",
+			"quote":  `say "hi"`,
+			"port":   "8080",
+			"host":   "example.com",
+		}
+		tx, err := ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://${host}/users
+body:
+  json: {colon: ${colon}, zero: ${zero}, bool: ${bool}, hash: ${hash}, lines: ${lines}, braces: ${braces}}
+`), WithResolver(resolver))
+		assert.Nil(t, err)
+		assert.Equal(t, "https://example.com/users", tx.URL.Target)
+		data, err := io.ReadAll(tx.Body)
+		assert.Nil(t, err)
+		assert.Equal(
+			t,
+			`{"colon":"p@ss: word","zero":"01234","bool":"true","hash":"# hash","lines":"first\nsecond","braces":"{x}"}`,
+			string(data),
+		)
+
+		tx, err = ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/users
+body:
+  yaml:
+    greeting: ${quote} to ${host}
+    port: !!int ${port}
+    literal: $${host}
+    static: 42
+`), WithResolver(resolver))
+		assert.Nil(t, err)
+		assert.Equal(t, "application/json", tx.Headers["Content-Type"])
+		data, err = io.ReadAll(tx.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"greeting":"say \"hi\" to example.com","port":8080,"literal":"${host}","static":42}`, string(data))
+	})
+
+	t.Run("json keeps declared content type", func(t *testing.T) {
+		tx, err := ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/users
+headers:
+  Content-Type: application/vnd.api+json
+body:
+  json: [1, 2, 3]
+`))
+		assert.Nil(t, err)
+		req, err := tx.Request()
+		assert.Nil(t, err)
+		assert.Equal(t, "application/vnd.api+json", req.Header.Get("Content-Type"))
+		data, err := io.ReadAll(req.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, `[1,2,3]`, string(data))
+	})
+
+	t.Run("multipart part without name", func(t *testing.T) {
+		_, err := ParseTransaction("", strings.NewReader(`
+method: POST
+url:
+  target: https://example.com/upload
+body:
+  multipart:
+    - value: nameless
+`))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestParseTransaction_Files(t *testing.T) {
+	wd := t.TempDir()
+	files := map[string]string{
+		"body.json":  `{"name": "${name}"}`,
+		"before.sqk": `session.set("name", "${name}");`,
+		"after.sqk":  `assert(response.body.name == "${name}");`,
+	}
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(wd, name), []byte(content), 0666))
+	}
+	cfg := `
+method: POST
+url:
+  target: https://example.com/users
+body:
+  file: body.json
+hooks:
+  before:
+    file: before.sqk
+  after:
+    file: after.sqk
+    interpolate: false
+`
+	read := func(r io.Reader) string {
+		t.Helper()
+		data, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		return string(data)
+	}
+
+	t.Run("interpolated", func(t *testing.T) {
+		tx, err := ParseTransaction(wd, strings.NewReader(cfg), WithResolver(MapResolver{"name": "pia"}))
+		assert.Nil(t, err)
+		assert.Equal(t, `{"name": "pia"}`, read(tx.Body))
+		assert.Equal(t, `session.set("name", "pia");`, read(tx.Hooks.Before))
+		assert.Equal(t, files["after.sqk"], read(tx.Hooks.After))
+	})
+
+	t.Run("without resolver", func(t *testing.T) {
+		tx, err := ParseTransaction(wd, strings.NewReader(cfg))
+		assert.Nil(t, err)
+		assert.Equal(t, files["body.json"], read(tx.Body))
+		assert.Equal(t, files["before.sqk"], read(tx.Hooks.Before))
+	})
+
+	t.Run("unresolved key", func(t *testing.T) {
+		tx, err := ParseTransaction(wd, strings.NewReader(cfg), WithResolver(MapResolver{}))
+		assert.Nil(t, err)
+		_, err = io.ReadAll(tx.Body)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("curl inlines interpolated body", func(t *testing.T) {
+		tx, err := ParseTransaction(wd, strings.NewReader(cfg), WithResolver(MapResolver{"name": "pia"}))
+		assert.Nil(t, err)
+		cmd, err := tx.Curl()
+		assert.Nil(t, err)
+		assert.Contains(t, cmd, `--data-raw '{"name": "pia"}'`)
+	})
+
+	t.Run("curl references body opted out of interpolation", func(t *testing.T) {
+		tx, err := ParseTransaction(wd, strings.NewReader(strings.Replace(
+			cfg, "file: body.json", "file: body.json\n  interpolate: false", 1,
+		)), WithResolver(MapResolver{"name": "pia"}))
+		assert.Nil(t, err)
+		cmd, err := tx.Curl()
+		assert.Nil(t, err)
+		assert.Contains(t, cmd, "--data-binary @"+filepath.Join(wd, "body.json"))
+	})
+}
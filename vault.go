@@ -0,0 +1,216 @@
+package pia
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// DefaultVault is the conventional path of the vault holding the secrets of a project.
+const DefaultVault = "secrets.vault"
+
+// ErrWrongPassphrase is returned when a vault cannot be decrypted using the supplied passphrase.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted vault")
+
+// vaultIterations is the number of PBKDF2 iterations used when creating a vault. Existing vaults record the number of
+// iterations they were created with.
+var vaultIterations = 600_000
+
+// vaultFile is the on-disk representation of a vault. The secrets are encrypted as a whole using AES-256-GCM with a key
+// derived from the passphrase using PBKDF2-HMAC-SHA256, which means that not even the names of the secrets are
+// readable without the passphrase.
+type vaultFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// Vault is a set of named secrets kept encrypted at rest in a single file. Changes are kept in memory until the vault
+// is saved.
+type Vault struct {
+	path       string
+	iterations int
+	salt       []byte
+	aead       cipher.AEAD
+	secrets    map[string]string
+}
+
+// OpenVault decrypts the vault at path using the passphrase. A vault that does not exist yet is created empty, and is
+// written to path once it is saved.
+func OpenVault(path, passphrase string) (*Vault, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		v := &Vault{path: path, iterations: vaultIterations, salt: salt, secrets: make(map[string]string)}
+		return v, v.derive(passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f vaultFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("malformed vault %s: %w", path, err)
+	}
+	if f.Version != 1 {
+		return nil, fmt.Errorf("unsupported vault version %d", f.Version)
+	}
+	if f.Iterations <= 0 || len(f.Salt) == 0 {
+		return nil, fmt.Errorf("malformed vault %s: missing key derivation parameters", path)
+	}
+	v := &Vault{path: path, iterations: f.Iterations, salt: f.Salt}
+	if err := v.derive(passphrase); err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != v.aead.NonceSize() {
+		return nil, fmt.Errorf("malformed vault %s: nonce of %d bytes, expected %d", path, len(f.Nonce), v.aead.NonceSize())
+	}
+	plain, err := v.aead.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if err := json.Unmarshal(plain, &v.secrets); err != nil {
+		return nil, fmt.Errorf("malformed vault %s: %w", path, err)
+	}
+	if v.secrets == nil {
+		v.secrets = make(map[string]string)
+	}
+	return v, nil
+}
+
+func (v *Vault) derive(passphrase string) error {
+	key, err := pbkdf2.Key(sha256.New, passphrase, v.salt, v.iterations, 32)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	v.aead, err = cipher.NewGCM(block)
+	return err
+}
+
+// Names returns the names of the secrets in lexical order.
+func (v *Vault) Names() []string {
+	return slices.Sorted(maps.Keys(v.secrets))
+}
+
+// Get returns the named secret and reports whether it exists.
+func (v *Vault) Get(name string) (string, bool) {
+	s, ok := v.secrets[name]
+	return s, ok
+}
+
+// Set stores the value as the named secret, replacing any previous value.
+func (v *Vault) Set(name, value string) {
+	v.secrets[name] = value
+}
+
+// Remove deletes the named secret and reports whether it existed.
+func (v *Vault) Remove(name string) bool {
+	_, ok := v.secrets[name]
+	delete(v.secrets, name)
+	return ok
+}
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (v *Vault) Resolve(k string) (string, error) {
+	s, ok := v.Get(k)
+	if !ok {
+		return "", fmt.Errorf("failed to resolve key '%s' from vault: %w", k, ErrKeyNotFound)
+	}
+	return s, nil
+}
+
+// Save encrypts the secrets using a fresh nonce and writes them to the file of the vault. The file is replaced
+// atomically and is only readable by its owner.
+func (v *Vault) Save() error {
+	plain, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(vaultFile{
+		Version:    1,
+		Iterations: v.iterations,
+		Salt:       v.salt,
+		Nonce:      nonce,
+		Data:       v.aead.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), filepath.Base(v.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), v.path)
+}
+
+// VaultResolver resolves keys against the secrets of the vault at Path. The vault is opened on first use, using the
+// passphrase returned by Passphrase, such that no passphrase is asked for unless a secret is actually referenced. Keys
+// never resolve if there is no vault at Path. A vault that fails to open, e.g. due to a mistyped passphrase, is opened
+// anew on the next use. It is safe for concurrent use.
+type VaultResolver struct {
+	Path       string
+	Passphrase func() (string, error)
+	mu         sync.Mutex
+	vault      *Vault
+}
+
+// Resolve implements the [pia.KeyResolver] interface.
+func (r *VaultResolver) Resolve(k string) (string, error) {
+	if _, err := os.Stat(r.Path); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to resolve key '%s': no vault at %s: %w", k, r.Path, ErrKeyNotFound)
+	}
+	vault, err := r.open()
+	if err != nil {
+		return "", err
+	}
+	return vault.Resolve(k)
+}
+
+// open returns the vault, opening it unless it has been opened successfully before.
+func (r *VaultResolver) open() (*Vault, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.vault != nil {
+		return r.vault, nil
+	}
+	passphrase, err := r.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	vault, err := OpenVault(r.Path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r.vault = vault
+	return vault, nil
+}
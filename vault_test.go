@@ -0,0 +1,93 @@
+package pia
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Key derivation is deliberately slow, which only makes the tests slow.
+	vaultIterations = 1
+}
+
+func TestVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultVault)
+	v, err := OpenVault(path, "passphrase")
+	assert.Nil(t, err)
+	assert.Empty(t, v.Names())
+	v.Set("token", "s3cr3t")
+	v.Set("api_key", "k3y")
+	v.Set("obsolete", "value")
+	assert.True(t, v.Remove("obsolete"))
+	assert.False(t, v.Remove("obsolete"))
+	assert.Nil(t, v.Save())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(data), "s3cr3t"))
+	assert.False(t, strings.Contains(string(data), "token"))
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	v, err = OpenVault(path, "passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api_key", "token"}, v.Names())
+	token, ok := v.Get("token")
+	assert.True(t, ok)
+	assert.Equal(t, "s3cr3t", token)
+
+	_, err = OpenVault(path, "wrong")
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+}
+
+func TestVaultResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultVault)
+	prompts := 0
+	passphrases := []string{"wrong", "passphrase"}
+	r := &VaultResolver{
+		Path: path,
+		Passphrase: func() (string, error) {
+			prompts++
+			return passphrases[min(prompts, len(passphrases))-1], nil
+		},
+	}
+	_, err := r.Resolve("token")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+	assert.Equal(t, 0, prompts, "no passphrase should be asked for without a vault")
+
+	v, err := OpenVault(path, "passphrase")
+	assert.Nil(t, err)
+	v.Set("token", "s3cr3t")
+	assert.Nil(t, v.Save())
+
+	_, err = r.Resolve("token")
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+	token, err := r.Resolve("token")
+	assert.Nil(t, err, "a failed open should not be remembered")
+	assert.Equal(t, "s3cr3t", token)
+	_, err = r.Resolve("missing")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+	assert.Equal(t, 2, prompts, "the vault should only be opened until it succeeds")
+}
+
+func TestOpenVault_Malformed(t *testing.T) {
+	files := map[string]string{
+		"short nonce":   `{"version": 1, "iterations": 1, "salt": "c2FsdA==", "nonce": "AA==", "data": ""}`,
+		"no iterations": `{"version": 1, "iterations": 0, "salt": "c2FsdA==", "nonce": "AAAAAAAAAAAAAAAA", "data": ""}`,
+		"empty salt":    `{"version": 1, "iterations": 1, "nonce": "AAAAAAAAAAAAAAAA", "data": ""}`,
+		"negative":      `{"version": 1, "iterations": -1, "salt": "c2FsdA==", "nonce": "AAAAAAAAAAAAAAAA", "data": ""}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultVault)
+			assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+			_, err := OpenVault(path, "passphrase")
+			assert.ErrorContains(t, err, "malformed vault")
+		})
+	}
+}